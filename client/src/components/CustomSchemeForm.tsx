@@ -137,11 +137,20 @@ const CustomSchemeForm = ({
           headers: {
             "Content-Type": "application/json",
             Authorization: `Bearer ${token}`,
+            "If-Match":
+              initialScheme.version !== undefined
+                ? `"${initialScheme.version}"`
+                : "*",
           },
           body: JSON.stringify(updatedScheme),
         });
         if (res.ok) {
-          if (onUpdateScheme) onUpdateScheme(updatedScheme);
+          const data = await res.json().catch(() => ({}));
+          if (onUpdateScheme) onUpdateScheme(data.data || updatedScheme);
+        } else if (res.status === 412) {
+          setError(
+            "This scheme was changed elsewhere. Reload it before saving again.",
+          );
         } else {
           const data = await res.json().catch(() => ({}));
           setError(data.message || "Failed to update color scheme.");
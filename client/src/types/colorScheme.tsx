@@ -2,7 +2,11 @@ export interface ColorScheme {
   id: string;
   name: string;
   author: string;
+  organization?: string;
   category: string;
+  version?: number;
+  stars?: number;
+  tags?: string[];
   colors: {
     black: string;
     red: string;
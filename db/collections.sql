@@ -0,0 +1,22 @@
+CREATE TABLE IF NOT EXISTS collections (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    owner TEXT NOT NULL,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS collections_owner_idx ON collections (owner);
+
+CREATE TABLE IF NOT EXISTS collection_schemes (
+    collection_id TEXT NOT NULL,
+    scheme_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    PRIMARY KEY (collection_id, scheme_id),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS collection_schemes_scheme_id_idx ON collection_schemes (scheme_id);
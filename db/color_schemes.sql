@@ -2,9 +2,30 @@ CREATE TABLE IF NOT EXISTS color_schemes (
     id TEXT PRIMARY KEY,
     name TEXT NOT NULL,
     author TEXT NOT NULL,
-    category TEXT NOT NULL
+    category TEXT NOT NULL,
+    variant_id TEXT REFERENCES color_schemes(id) ON DELETE SET NULL,
+    public BOOLEAN NOT NULL DEFAULT FALSE,
+    duplicate_of TEXT REFERENCES color_schemes(id) ON DELETE SET NULL,
+    version INTEGER NOT NULL DEFAULT 1,
+    forked_from TEXT REFERENCES color_schemes(id) ON DELETE SET NULL,
+    forked_revision INTEGER,
+    star_count INTEGER NOT NULL DEFAULT 0,
+    organization TEXT REFERENCES organizations(name) ON DELETE SET NULL
 );
 
+-- Upgrades databases created before these columns existed
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS variant_id TEXT REFERENCES color_schemes(id) ON DELETE SET NULL;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS public BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS duplicate_of TEXT REFERENCES color_schemes(id) ON DELETE SET NULL;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS forked_from TEXT REFERENCES color_schemes(id) ON DELETE SET NULL;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS forked_revision INTEGER;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS star_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE color_schemes ADD COLUMN IF NOT EXISTS organization TEXT REFERENCES organizations(name) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS color_schemes_forked_from_idx ON color_schemes (forked_from);
+CREATE INDEX IF NOT EXISTS color_schemes_organization_idx ON color_schemes (organization);
+
 CREATE TABLE IF NOT EXISTS color_scheme_colors (
     scheme_id TEXT NOT NULL,
     color_key TEXT NOT NULL,
@@ -12,3 +33,76 @@ CREATE TABLE IF NOT EXISTS color_scheme_colors (
     PRIMARY KEY (scheme_id, color_key),
     FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
 );
+
+CREATE TABLE IF NOT EXISTS color_scheme_accessibility (
+    scheme_id TEXT PRIMARY KEY,
+    score DOUBLE PRECISION NOT NULL,
+    min_contrast DOUBLE PRECISION NOT NULL,
+    failing_aa INTEGER NOT NULL,
+    report JSONB NOT NULL,
+    computed_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_accessibility_score_idx ON color_scheme_accessibility (score);
+
+CREATE TABLE IF NOT EXISTS color_scheme_metrics (
+    scheme_id TEXT PRIMARY KEY,
+    background_luminance DOUBLE PRECISION NOT NULL,
+    foreground_contrast DOUBLE PRECISION NOT NULL,
+    average_chroma DOUBLE PRECISION NOT NULL,
+    temperature DOUBLE PRECISION NOT NULL,
+    traits TEXT[] NOT NULL DEFAULT '{}',
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_metrics_traits_idx ON color_scheme_metrics USING GIN (traits);
+
+CREATE TABLE IF NOT EXISTS color_scheme_features (
+    scheme_id TEXT PRIMARY KEY,
+    background_l DOUBLE PRECISION NOT NULL,
+    slots JSONB NOT NULL,
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_features_background_l_idx ON color_scheme_features (background_l);
+
+CREATE TABLE IF NOT EXISTS color_scheme_revisions (
+    scheme_id TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    category TEXT NOT NULL,
+    colors JSONB NOT NULL,
+    editor TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (scheme_id, revision),
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS tags (
+    id SERIAL PRIMARY KEY,
+    name TEXT NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS tags_name_prefix_idx ON tags (name text_pattern_ops);
+
+CREATE TABLE IF NOT EXISTS color_scheme_tags (
+    scheme_id TEXT NOT NULL,
+    tag_id INTEGER NOT NULL,
+    PRIMARY KEY (scheme_id, tag_id),
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE,
+    FOREIGN KEY (tag_id) REFERENCES tags(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_tags_tag_id_idx ON color_scheme_tags (tag_id);
+
+CREATE TABLE IF NOT EXISTS color_scheme_stars (
+    scheme_id TEXT NOT NULL,
+    username TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (scheme_id, username),
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_stars_username_idx ON color_scheme_stars (username);
+CREATE INDEX IF NOT EXISTS color_scheme_stars_created_at_idx ON color_scheme_stars (created_at);
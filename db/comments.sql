@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS color_scheme_comments (
+    id TEXT PRIMARY KEY,
+    scheme_id TEXT NOT NULL,
+    parent_id TEXT,
+    author TEXT NOT NULL,
+    body TEXT NOT NULL,
+    kind TEXT NOT NULL CHECK (kind IN ('comment', 'suggestion')),
+    changes JSONB,
+    status TEXT CHECK (status IN ('open', 'accepted', 'rejected')),
+    resolved_by TEXT,
+    resolved_at TIMESTAMPTZ,
+    revision INTEGER,
+    deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (scheme_id) REFERENCES color_schemes(id) ON DELETE CASCADE,
+    FOREIGN KEY (parent_id) REFERENCES color_scheme_comments(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS color_scheme_comments_scheme_id_idx ON color_scheme_comments (scheme_id, created_at);
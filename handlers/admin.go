@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/services"
+)
+
+type adminHandler struct {
+	adminService services.AdminService
+}
+
+func NewAdminHandler(adminService services.AdminService) *adminHandler {
+	return &adminHandler{
+		adminService: adminService,
+	}
+}
+
+func (h *adminHandler) ListUsers(c *gin.Context) {
+	users, err := h.adminService.ListUsers(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: "Failed to list users",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    users,
+	})
+}
+
+func (h *adminHandler) SetUserDisabled(c *gin.Context) {
+	var req models.DisableRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	role, _ := c.Get("role")
+	err := h.adminService.SetUserDisabled(c.Request.Context(), c.GetString("username"), role.(models.Role), c.Param("username"), *req.Disabled)
+	if err != nil {
+		respondAdminError(c, err, "Failed to update account")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *adminHandler) SetUserRole(c *gin.Context) {
+	var req models.RoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.adminService.SetUserRole(c.Request.Context(), c.GetString("username"), c.Param("username"), req.Role); err != nil {
+		respondAdminError(c, err, "Failed to update role")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *adminHandler) ReassignColorScheme(c *gin.Context) {
+	var req models.AuthorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.adminService.ReassignColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), req.Author); err != nil {
+		respondAdminError(c, err, "Failed to reassign color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *adminHandler) DeleteColorScheme(c *gin.Context) {
+	if err := h.adminService.DeleteColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id")); err != nil {
+		respondAdminError(c, err, "Failed to delete color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func respondAdminError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "User not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrInvalidRole), errors.Is(err, services.ErrSelfAction):
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+	default:
+		respondColorSchemeError(c, err, message)
+	}
+}
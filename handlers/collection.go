@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/services"
+)
+
+type collectionHandler struct {
+	collectionService services.CollectionService
+}
+
+func NewCollectionHandler(collectionService services.CollectionService) *collectionHandler {
+	return &collectionHandler{
+		collectionService: collectionService,
+	}
+}
+
+func (h *collectionHandler) ListCollections(c *gin.Context) {
+	collections, err := h.collectionService.ListCollections(c.Request.Context(), c.GetString("username"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to list collections")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    collections,
+	})
+}
+
+func (h *collectionHandler) GetCollection(c *gin.Context) {
+	collection, err := h.collectionService.GetCollection(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    collection,
+	})
+}
+
+func (h *collectionHandler) CreateCollection(c *gin.Context) {
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	collection, err := h.collectionService.CreateCollection(c.Request.Context(), c.GetString("username"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to create collection")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Message: "Success",
+		Code:    http.StatusCreated,
+		Data:    collection,
+	})
+}
+
+func (h *collectionHandler) UpdateCollection(c *gin.Context) {
+	var req models.CollectionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	collection, err := h.collectionService.UpdateCollection(c.Request.Context(), c.GetString("username"), c.Param("id"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to update collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    collection,
+	})
+}
+
+func (h *collectionHandler) DeleteCollection(c *gin.Context) {
+	if err := h.collectionService.DeleteCollection(c.Request.Context(), c.GetString("username"), c.Param("id")); err != nil {
+		respondColorSchemeError(c, err, "Failed to delete collection")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *collectionHandler) ExportCollection(c *gin.Context) {
+	file, err := h.collectionService.ExportCollection(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Query("format"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to export collection")
+		return
+	}
+
+	respondFile(c, file)
+}
+
+// respondFile sends an exported file as a download.
+func respondFile(c *gin.Context, file *models.ExportFile) {
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.Name}))
+	c.Data(http.StatusOK, file.ContentType, file.Data)
+}
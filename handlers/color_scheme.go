@@ -1,10 +1,18 @@
 package handlers
 
 import (
+	"errors"
+	"io"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/jsonpatch"
+	"github.com/nqvinh00/colorscheme/pkg/tags"
 	"github.com/nqvinh00/colorscheme/services"
 )
 
@@ -28,7 +36,16 @@ func (h *colorSchemeHandler) GetAllColorSchemesByAuthor(c *gin.Context) {
 		return
 	}
 
-	colorSchemes, err := h.colorSchemeService.GetAllColorSchemesByAuthor(c.Request.Context(), username.(string))
+	filter, err := parseColorSchemeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	colorSchemes, err := h.colorSchemeService.GetAllColorSchemesByAuthor(c.Request.Context(), username.(string), filter)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, models.Response{
 			Message: "Failed to get all color schemes",
@@ -45,13 +62,9 @@ func (h *colorSchemeHandler) GetAllColorSchemesByAuthor(c *gin.Context) {
 }
 
 func (h *colorSchemeHandler) GetColorSchemeById(c *gin.Context) {
-	id := c.Param("id")
-	colorScheme, err := h.colorSchemeService.GetColorSchemeById(c.Request.Context(), id)
+	colorScheme, err := h.colorSchemeService.GetColorSchemeById(c.Request.Context(), c.GetString("username"), c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to get color scheme",
-			Code:    http.StatusInternalServerError,
-		})
+		respondColorSchemeError(c, err, "Failed to get color scheme")
 		return
 	}
 
@@ -63,6 +76,7 @@ func (h *colorSchemeHandler) GetColorSchemeById(c *gin.Context) {
 		return
 	}
 
+	c.Header("ETag", etag(colorScheme.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Message: "Success",
 		Code:    http.StatusOK,
@@ -71,6 +85,15 @@ func (h *colorSchemeHandler) GetColorSchemeById(c *gin.Context) {
 }
 
 func (h *colorSchemeHandler) CreateColorScheme(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
 	var colorScheme models.ColorScheme
 	if err := c.ShouldBindJSON(&colorScheme); err != nil {
 		c.JSON(http.StatusBadRequest, models.Response{
@@ -80,22 +103,30 @@ func (h *colorSchemeHandler) CreateColorScheme(c *gin.Context) {
 		return
 	}
 
-	if err := h.colorSchemeService.CreateColorScheme(c.Request.Context(), colorScheme); err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to create color scheme",
-			Code:    http.StatusInternalServerError,
-		})
+	created, err := h.colorSchemeService.CreateColorScheme(c.Request.Context(), username.(string), colorScheme)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to create color scheme")
 		return
 	}
 
+	c.Header("ETag", etag(created.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Message: "Success",
 		Code:    http.StatusOK,
-		Data:    colorScheme,
+		Data:    created,
 	})
 }
 
 func (h *colorSchemeHandler) UpdateColorScheme(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
 	var colorScheme models.ColorScheme
 	if err := c.ShouldBindJSON(&colorScheme); err != nil {
 		c.JSON(http.StatusBadRequest, models.Response{
@@ -104,34 +135,809 @@ func (h *colorSchemeHandler) UpdateColorScheme(c *gin.Context) {
 		})
 		return
 	}
+	// PUT /color-schemes/:id takes the ID from the path
+	if id := c.Param("id"); id != "" {
+		colorScheme.ID = id
+	}
 
-	if err := h.colorSchemeService.UpdateColorScheme(c.Request.Context(), colorScheme); err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to update color scheme",
-			Code:    http.StatusInternalServerError,
+	version, ok := h.parseIfMatch(c, colorScheme.ID)
+	if !ok {
+		return
+	}
+	colorScheme.Version = version
+
+	updated, err := h.colorSchemeService.UpdateColorScheme(c.Request.Context(), username.(string), colorScheme)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to update color scheme")
+		return
+	}
+
+	c.Header("ETag", etag(updated.Version))
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    updated,
+	})
+}
+
+// Largest patch accepted by PatchColorScheme.
+const maxPatchBytes = 1 << 20
+
+// PatchColorScheme accepts a JSON Merge Patch or a JSON Patch. Plain
+// application/json is read as a JSON Patch when it is an array and as a
+// merge patch otherwise.
+func (h *colorSchemeHandler) PatchColorScheme(c *gin.Context) {
+	version, ok := h.parseIfMatch(c, c.Param("id"))
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxPatchBytes)
+	patch, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.Response{
+				Message: "Patch is too large",
+				Code:    http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
 		})
 		return
 	}
 
+	contentType := c.ContentType()
+	if contentType == "application/json" {
+		contentType = models.MergePatchContentType
+		if jsonpatch.IsJSONPatch(patch) {
+			contentType = models.JSONPatchContentType
+		}
+	}
+	if contentType != models.MergePatchContentType && contentType != models.JSONPatchContentType {
+		c.Header("Accept-Patch", models.MergePatchContentType+", "+models.JSONPatchContentType)
+		c.JSON(http.StatusUnsupportedMediaType, models.Response{
+			Message: "Unsupported patch format",
+			Code:    http.StatusUnsupportedMediaType,
+		})
+		return
+	}
+
+	updated, err := h.colorSchemeService.PatchColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), version, contentType, patch)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to patch color scheme")
+		return
+	}
+
+	c.Header("ETag", etag(updated.Version))
 	c.JSON(http.StatusOK, models.Response{
 		Message: "Success",
 		Code:    http.StatusOK,
-		Data:    colorScheme,
+		Data:    updated,
 	})
 }
 
 func (h *colorSchemeHandler) DeleteColorScheme(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
 	id := c.Param("id")
-	if err := h.colorSchemeService.DeleteColorScheme(c.Request.Context(), id); err != nil {
-		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to delete color scheme",
-			Code:    http.StatusInternalServerError,
+	version, ok := h.parseIfMatch(c, id)
+	if !ok {
+		return
+	}
+
+	if err := h.colorSchemeService.DeleteColorScheme(c.Request.Context(), username.(string), id, version); err != nil {
+		respondColorSchemeError(c, err, "Failed to delete color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *colorSchemeHandler) GetAccessibilityReport(c *gin.Context) {
+	report, err := h.colorSchemeService.GetAccessibilityReport(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get accessibility report")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    report,
+	})
+}
+
+func (h *colorSchemeHandler) SimulateColorVision(c *gin.Context) {
+	severity := 1.0
+	if v := c.Query("severity"); v != "" {
+		var err error
+		severity, err = strconv.ParseFloat(v, 64)
+		if err != nil || math.IsNaN(severity) || math.IsInf(severity, 0) {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Message: "severity must be a number",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	simulations, err := h.colorSchemeService.SimulateColorVision(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Query("type"), severity)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to simulate color vision")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    simulations,
+	})
+}
+
+// Bounds on the number of results returned by SimilarColorSchemes.
+const (
+	defaultSimilarLimit = 10
+	maxSimilarLimit     = 50
+)
+
+func (h *colorSchemeHandler) SimilarColorSchemes(c *gin.Context) {
+	limit, ok := parseLimit(c, defaultSimilarLimit, maxSimilarLimit)
+	if !ok {
+		return
+	}
+
+	similar, err := h.colorSchemeService.SimilarColorSchemes(c.Request.Context(), c.GetString("username"), c.Param("id"), limit)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to find similar color schemes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    similar,
+	})
+}
+
+func (h *colorSchemeHandler) DiffColorSchemes(c *gin.Context) {
+	diff, err := h.colorSchemeService.DiffColorSchemes(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Param("other"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to diff color schemes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    diff,
+	})
+}
+
+func (h *colorSchemeHandler) MergeColorScheme(c *gin.Context) {
+	var req models.MergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	result, err := h.colorSchemeService.MergeColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to merge color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    result,
+	})
+}
+
+func (h *colorSchemeHandler) MapToXterm(c *gin.Context) {
+	report, err := h.colorSchemeService.MapToXterm(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to map color scheme to xterm colors")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    report,
+	})
+}
+
+func (h *colorSchemeHandler) ListRevisions(c *gin.Context) {
+	revisions, err := h.colorSchemeService.ListRevisions(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to list revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    revisions,
+	})
+}
+
+func (h *colorSchemeHandler) GetRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "revision must be a number",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	rev, err := h.colorSchemeService.GetRevision(c.Request.Context(), c.GetString("username"), c.Param("id"), revision)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    rev,
+	})
+}
+
+func (h *colorSchemeHandler) RestoreRevision(c *gin.Context) {
+	revision, err := strconv.Atoi(c.Param("revision"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "revision must be a number",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	colorScheme, err := h.colorSchemeService.RestoreRevision(c.Request.Context(), c.GetString("username"), c.Param("id"), revision)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to restore revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    colorScheme,
+	})
+}
+
+func (h *colorSchemeHandler) DeriveColorScheme(c *gin.Context) {
+	var req models.DeriveRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
 		})
 		return
 	}
 
+	derived, err := h.colorSchemeService.DeriveColorScheme(c.Request.Context(), c.GetString("username"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to derive color scheme")
+		return
+	}
+
 	c.JSON(http.StatusOK, models.Response{
 		Message: "Success",
 		Code:    http.StatusOK,
+		Data:    derived,
+	})
+}
+
+func (h *colorSchemeHandler) CreateVariant(c *gin.Context) {
+	variant, err := h.colorSchemeService.CreateVariant(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to create color scheme variant")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Message: "Success",
+		Code:    http.StatusCreated,
+		Data:    variant,
 	})
 }
+
+func (h *colorSchemeHandler) ForkColorScheme(c *gin.Context) {
+	// The body is optional
+	var req models.ForkRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Message: "Invalid request",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	fork, err := h.colorSchemeService.ForkColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to fork color scheme")
+		return
+	}
+
+	c.Header("ETag", etag(fork.Version))
+	c.JSON(http.StatusCreated, models.Response{
+		Message: "Success",
+		Code:    http.StatusCreated,
+		Data:    fork,
+	})
+}
+
+func (h *colorSchemeHandler) GetLineage(c *gin.Context) {
+	lineage, err := h.colorSchemeService.GetLineage(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get color scheme lineage")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    lineage,
+	})
+}
+
+func (h *colorSchemeHandler) GenerateColorScheme(c *gin.Context) {
+	var req models.GenerateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	generated, err := h.colorSchemeService.GenerateColorScheme(c.Request.Context(), c.GetString("username"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to generate color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    generated,
+	})
+}
+
+// Largest image accepted by ExtractColorScheme.
+const maxImageUploadBytes = 10 << 20
+
+func (h *colorSchemeHandler) ExtractColorScheme(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageUploadBytes)
+
+	file, err := c.FormFile("image")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, models.Response{
+				Message: "Image is too large",
+				Code:    http.StatusRequestEntityTooLarge,
+			})
+			return
+		}
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var req models.ExtractRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	image, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+	defer image.Close()
+
+	extracted, err := h.colorSchemeService.ExtractColorScheme(c.Request.Context(), c.GetString("username"), req, image)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to extract color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    extracted,
+	})
+}
+
+// parseColorSchemeFilter reads the optional list filters from the query
+// string.
+func parseColorSchemeFilter(c *gin.Context) (models.ColorSchemeFilter, error) {
+	filter := models.ColorSchemeFilter{
+		Category: c.Query("category"),
+		Traits:   c.QueryArray("trait"),
+	}
+
+	bounds := []struct {
+		param string
+		dest  **float64
+	}{
+		{"min_accessibility", &filter.MinAccessibility},
+		{"min_chroma", &filter.MinChroma},
+		{"max_chroma", &filter.MaxChroma},
+		{"min_temperature", &filter.MinTemperature},
+		{"max_temperature", &filter.MaxTemperature},
+		{"min_contrast", &filter.MinContrast},
+	}
+	for _, b := range bounds {
+		v := c.Query(b.param)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return filter, errors.New(b.param + " must be a number")
+		}
+		*b.dest = &f
+	}
+
+	if t := c.QueryArray("tag"); len(t) > 0 {
+		var err error
+		if filter.Tags, err = tags.NormalizeAll(t); err != nil {
+			return filter, err
+		}
+	}
+
+	return filter, nil
+}
+
+const (
+	defaultTagLimit = 20
+	maxTagLimit     = 100
+)
+
+// ListTags serves tag counts and autocompletion: ?prefix= matches the
+// start of the normalized tag.
+func (h *colorSchemeHandler) ListTags(c *gin.Context) {
+	limit, ok := parseLimit(c, defaultTagLimit, maxTagLimit)
+	if !ok {
+		return
+	}
+
+	var prefix string
+	if v := c.Query("prefix"); strings.TrimSpace(v) != "" {
+		var err error
+		if prefix, err = tags.Normalize(v); err != nil {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Message: err.Error(),
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+	}
+
+	counts, err := h.colorSchemeService.ListTags(c.Request.Context(), c.GetString("username"), prefix, limit)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to list tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    counts,
+	})
+}
+
+func (h *colorSchemeHandler) GetColorSchemesByTag(c *gin.Context) {
+	filter, err := parseColorSchemeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	var ok bool
+	if filter.Limit, ok = parseLimit(c, defaultSimilarLimit, maxSimilarLimit); !ok {
+		return
+	}
+
+	colorSchemes, err := h.colorSchemeService.GetColorSchemesByTag(c.Request.Context(), c.GetString("username"), c.Param("tag"), filter)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get color schemes by tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    colorSchemes,
+	})
+}
+
+func (h *colorSchemeHandler) TransferColorScheme(c *gin.Context) {
+	var req models.TransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	colorScheme, err := h.colorSchemeService.TransferColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), req.Organization)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to transfer color scheme")
+		return
+	}
+
+	c.Header("ETag", etag(colorScheme.Version))
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    colorScheme,
+	})
+}
+
+// ExportColorScheme downloads a scheme as a terminal configuration file,
+// ?format= picking the terminal.
+func (h *colorSchemeHandler) ExportColorScheme(c *gin.Context) {
+	file, err := h.colorSchemeService.ExportColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Query("format"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to export color scheme")
+		return
+	}
+
+	respondFile(c, file)
+}
+
+func (h *colorSchemeHandler) StarColorScheme(c *gin.Context) {
+	status, err := h.colorSchemeService.StarColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to star color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    status,
+	})
+}
+
+func (h *colorSchemeHandler) UnstarColorScheme(c *gin.Context) {
+	status, err := h.colorSchemeService.UnstarColorScheme(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to unstar color scheme")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    status,
+	})
+}
+
+func (h *colorSchemeHandler) GetStarredColorSchemes(c *gin.Context) {
+	filter, err := parseColorSchemeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	colorSchemes, err := h.colorSchemeService.GetStarredColorSchemes(c.Request.Context(), c.GetString("username"), filter)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get starred color schemes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    colorSchemes,
+	})
+}
+
+func (h *colorSchemeHandler) GetTrendingColorSchemes(c *gin.Context) {
+	limit, ok := parseLimit(c, defaultSimilarLimit, maxSimilarLimit)
+	if !ok {
+		return
+	}
+
+	trending, err := h.colorSchemeService.GetTrendingColorSchemes(c.Request.Context(), limit)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get trending color schemes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    trending,
+	})
+}
+
+// parseLimit reads ?limit=, between 1 and max. When it is invalid it writes
+// the response and returns false.
+func parseLimit(c *gin.Context, def, max int) (int, bool) {
+	v := c.Query("limit")
+	if v == "" {
+		return def, true
+	}
+
+	limit, err := strconv.Atoi(v)
+	if err != nil || limit < 1 || limit > max {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "limit must be between 1 and " + strconv.Itoa(max),
+			Code:    http.StatusBadRequest,
+		})
+		return 0, false
+	}
+	return limit, true
+}
+
+// etag formats a scheme version as a strong entity tag.
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+// parseIfMatch reads the version a write to scheme id is based on from the
+// If-Match header, "*" matching any version. A list of tags matches when
+// any of them is the current version. If-Match compares strongly (RFC 7232
+// section 3.1), so weak tags never match. When the header is missing or
+// matches no version it writes the response and returns false.
+func (h *colorSchemeHandler) parseIfMatch(c *gin.Context, id string) (int, bool) {
+	value := strings.TrimSpace(c.GetHeader("If-Match"))
+	if value == "" {
+		c.JSON(http.StatusPreconditionRequired, models.Response{
+			Message: "If-Match header is required",
+			Code:    http.StatusPreconditionRequired,
+		})
+		return 0, false
+	}
+	if value == "*" {
+		return models.AnyVersion, true
+	}
+
+	var versions []int
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.TrimSpace(tag)
+		// Weak tags and tags that aren't versions can never match
+		if strings.HasPrefix(tag, "W/") {
+			continue
+		}
+		if version, err := strconv.Atoi(strings.Trim(tag, `"`)); err == nil && version >= 1 {
+			versions = append(versions, version)
+		}
+	}
+	if len(versions) == 1 {
+		return versions[0], true
+	}
+
+	// Pick the listed version that is current, the write still checks it
+	// did not change since. With none current the client gets the conflict
+	// and the version to retry with.
+	current, err := h.colorSchemeService.GetColorSchemeById(c.Request.Context(), c.GetString("username"), id)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get color scheme")
+		return 0, false
+	}
+	if slices.Contains(versions, current.Version) {
+		return current.Version, true
+	}
+	respondColorSchemeError(c, &services.VersionConflictError{Current: current.Version}, "Failed to get color scheme")
+	return 0, false
+}
+
+func respondColorSchemeError(c *gin.Context, err error, message string) {
+	if respondValidationError(c, err) {
+		return
+	}
+
+	var conflict *services.VersionConflictError
+	switch {
+	case errors.As(err, &conflict):
+		c.Header("ETag", etag(conflict.Current))
+		c.JSON(http.StatusPreconditionFailed, models.Response{
+			Message: "Color scheme has been modified",
+			Code:    http.StatusPreconditionFailed,
+			Data:    models.VersionConflict{CurrentVersion: conflict.Current},
+		})
+	case errors.Is(err, services.ErrColorSchemeNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "Color scheme not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrCollectionNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "Collection not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrOrganizationNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "Organization not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrUserNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "User not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrOrganizationExists):
+		c.JSON(http.StatusConflict, models.Response{
+			Message: "Organization already exists",
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, services.ErrLastOwner):
+		c.JSON(http.StatusConflict, models.Response{
+			Message: "An organization needs at least one owner",
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, services.ErrRevisionNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "Revision not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrCommentNotFound):
+		c.JSON(http.StatusNotFound, models.Response{
+			Message: "Comment not found",
+			Code:    http.StatusNotFound,
+		})
+	case errors.Is(err, services.ErrSuggestionResolved):
+		c.JSON(http.StatusConflict, models.Response{
+			Message: "Suggestion has already been resolved",
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, services.ErrVariantExists):
+		c.JSON(http.StatusConflict, models.Response{
+			Message: "Color scheme already has a variant",
+			Code:    http.StatusConflict,
+		})
+	case errors.Is(err, services.ErrForbidden):
+		c.JSON(http.StatusForbidden, models.Response{
+			Message: "Forbidden",
+			Code:    http.StatusForbidden,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: message,
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
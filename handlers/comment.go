@@ -0,0 +1,96 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/services"
+)
+
+type commentHandler struct {
+	commentService services.CommentService
+}
+
+func NewCommentHandler(commentService services.CommentService) *commentHandler {
+	return &commentHandler{
+		commentService: commentService,
+	}
+}
+
+func (h *commentHandler) ListComments(c *gin.Context) {
+	comments, err := h.commentService.ListComments(c.Request.Context(), c.GetString("username"), c.Param("id"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to list comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    comments,
+	})
+}
+
+func (h *commentHandler) CreateComment(c *gin.Context) {
+	var req models.CommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(c.Request.Context(), c.GetString("username"), c.Param("id"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Message: "Success",
+		Code:    http.StatusCreated,
+		Data:    comment,
+	})
+}
+
+func (h *commentHandler) DeleteComment(c *gin.Context) {
+	if err := h.commentService.DeleteComment(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Param("comment")); err != nil {
+		respondColorSchemeError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *commentHandler) AcceptSuggestion(c *gin.Context) {
+	comment, err := h.commentService.AcceptSuggestion(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Param("comment"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to accept suggestion")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    comment,
+	})
+}
+
+func (h *commentHandler) RejectSuggestion(c *gin.Context) {
+	comment, err := h.commentService.RejectSuggestion(c.Request.Context(), c.GetString("username"), c.Param("id"), c.Param("comment"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to reject suggestion")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    comment,
+	})
+}
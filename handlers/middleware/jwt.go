@@ -1,15 +1,24 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/nqvinh00/colorscheme/models"
 	"github.com/nqvinh00/colorscheme/pkg/utils"
 )
 
-func AuthMiddleware(secretKey string) gin.HandlerFunc {
+// AccountStatus reports a user's current role and whether their tokens must
+// be rejected before they expire, e.g. after an admin disabled the account.
+// The role is read on every request so that a demotion applies at once.
+type AccountStatus interface {
+	AccountStatus(ctx context.Context, username string) (models.Role, bool, error)
+}
+
+func AuthMiddleware(secretKey string, accounts AccountStatus) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
@@ -24,7 +33,29 @@ func AuthMiddleware(secretKey string) gin.HandlerFunc {
 			return
 		}
 
-		c.Set("username", token.Claims.(jwt.MapClaims)["username"])
+		claims := token.Claims.(jwt.MapClaims)
+		if pending, _ := claims["mfa_pending"].(bool); pending {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Two-factor authentication required"})
+			return
+		}
+
+		username, _ := claims["username"].(string)
+		role, disabled, err := accounts.AccountStatus(c.Request.Context(), username)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to check account"})
+			return
+		}
+		if disabled {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Account disabled"})
+			return
+		}
+
+		if !role.Valid() {
+			role = models.RoleUser
+		}
+
+		c.Set("username", username)
+		c.Set("role", role)
 		c.Next()
 	}
 }
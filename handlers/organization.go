@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/services"
+)
+
+type organizationHandler struct {
+	organizationService services.OrganizationService
+}
+
+func NewOrganizationHandler(organizationService services.OrganizationService) *organizationHandler {
+	return &organizationHandler{
+		organizationService: organizationService,
+	}
+}
+
+func (h *organizationHandler) ListOrganizations(c *gin.Context) {
+	orgs, err := h.organizationService.ListOrganizations(c.Request.Context(), c.GetString("username"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to list organizations")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    orgs,
+	})
+}
+
+func (h *organizationHandler) GetOrganization(c *gin.Context) {
+	org, err := h.organizationService.GetOrganization(c.Request.Context(), c.GetString("username"), c.Param("org"))
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    org,
+	})
+}
+
+func (h *organizationHandler) CreateOrganization(c *gin.Context) {
+	var req models.OrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	org, err := h.organizationService.CreateOrganization(c.Request.Context(), c.GetString("username"), req)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to create organization")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.Response{
+		Message: "Success",
+		Code:    http.StatusCreated,
+		Data:    org,
+	})
+}
+
+func (h *organizationHandler) DeleteOrganization(c *gin.Context) {
+	if err := h.organizationService.DeleteOrganization(c.Request.Context(), c.GetString("username"), c.Param("org")); err != nil {
+		respondColorSchemeError(c, err, "Failed to delete organization")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *organizationHandler) SetMember(c *gin.Context) {
+	var req models.OrgMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.organizationService.SetMember(c.Request.Context(), c.GetString("username"), c.Param("org"), c.Param("username"), req.Role); err != nil {
+		respondColorSchemeError(c, err, "Failed to set organization member")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *organizationHandler) RemoveMember(c *gin.Context) {
+	if err := h.organizationService.RemoveMember(c.Request.Context(), c.GetString("username"), c.Param("org"), c.Param("username")); err != nil {
+		respondColorSchemeError(c, err, "Failed to remove organization member")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+	})
+}
+
+func (h *organizationHandler) GetOrganizationColorSchemes(c *gin.Context) {
+	filter, err := parseColorSchemeFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	colorSchemes, err := h.organizationService.GetOrganizationColorSchemes(c.Request.Context(), c.GetString("username"), c.Param("org"), filter)
+	if err != nil {
+		respondColorSchemeError(c, err, "Failed to get organization color schemes")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    colorSchemes,
+	})
+}
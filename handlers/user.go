@@ -1,12 +1,14 @@
 package handlers
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/nqvinh00/colorscheme/models"
 	"github.com/nqvinh00/colorscheme/services"
-	"golang.org/x/crypto/bcrypt"
 )
 
 type userHandler struct {
@@ -30,30 +32,248 @@ func (h *userHandler) CreateAccount(c *gin.Context) {
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	token, err := h.userService.CreateAccount(c.Request.Context(), req.Username, req.Password, req.Email)
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to hash password",
+			Message: "Failed to create account",
 			Code:    http.StatusInternalServerError,
 		})
 		return
 	}
 
-	token, err := h.userService.CreateAccount(c.Request.Context(), req.Username, string(hashed))
+	c.JSON(http.StatusCreated, gin.H{"token": token})
+}
+
+// Login handles user authentication
+func (h *userHandler) Login(c *gin.Context) {
+	var req models.AuthRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	result, err := h.userService.Login(c.Request.Context(), req.Username, req.Password, c.ClientIP())
 	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	if result.MFARequired {
+		c.JSON(http.StatusAccepted, models.Response{
+			Message: "Two-factor authentication required",
+			Code:    http.StatusAccepted,
+			Data:    result,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Login successful",
+		Code:    http.StatusOK,
+		Data:    result.Token,
+	})
+}
+
+// VerifyMFA completes a two-factor login
+func (h *userHandler) VerifyMFA(c *gin.Context) {
+	var req models.MFALoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	token, err := h.userService.VerifyMFA(c.Request.Context(), req.MFAToken, req.Code, c.ClientIP())
+	if err != nil {
+		respondLoginError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Login successful",
+		Code:    http.StatusOK,
+		Data:    token,
+	})
+}
+
+// UpdateEmail sets the caller's email address and sends a verification link
+func (h *userHandler) UpdateEmail(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.userService.UpdateEmail(c.Request.Context(), username.(string), req.Email); err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
 		c.JSON(http.StatusInternalServerError, models.Response{
-			Message: "Failed to create account",
+			Message: "Failed to update email",
 			Code:    http.StatusInternalServerError,
 		})
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"token": token})
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Verification email sent",
+		Code:    http.StatusOK,
+	})
 }
 
-// Login handles user authentication
-func (h *userHandler) Login(c *gin.Context) {
-	var req models.AuthRequest
+// VerifyEmail redeems an email verification token
+func (h *userHandler) VerifyEmail(c *gin.Context) {
+	var req models.TokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.userService.VerifyEmail(c.Request.Context(), req.Token); err != nil {
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Message: "Invalid or expired token",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: "Failed to verify email",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Email verified",
+		Code:    http.StatusOK,
+	})
+}
+
+// RequestPasswordReset emails a reset link to a verified address
+func (h *userHandler) RequestPasswordReset(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(c.Request.Context(), req.Email); err != nil {
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: "Failed to request password reset",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "If the address belongs to a verified account, a reset link has been sent",
+		Code:    http.StatusOK,
+	})
+}
+
+// ResetPassword sets a new password using a reset token
+func (h *userHandler) ResetPassword(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.userService.ResetPassword(c.Request.Context(), req.Token, req.Password); err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		if errors.Is(err, services.ErrInvalidToken) {
+			c.JSON(http.StatusBadRequest, models.Response{
+				Message: "Invalid or expired token",
+				Code:    http.StatusBadRequest,
+			})
+			return
+		}
+
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: "Failed to reset password",
+			Code:    http.StatusInternalServerError,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Password updated",
+		Code:    http.StatusOK,
+	})
+}
+
+// EnrollTOTP starts two-factor enrollment and returns the otpauth:// URI
+func (h *userHandler) EnrollTOTP(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	enrollment, err := h.userService.EnrollTOTP(c.Request.Context(), username.(string))
+	if err != nil {
+		respondMFAError(c, err, "Failed to enroll two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Success",
+		Code:    http.StatusOK,
+		Data:    enrollment,
+	})
+}
+
+// ConfirmTOTP enables two-factor authentication and returns recovery codes
+func (h *userHandler) ConfirmTOTP(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req models.CodeRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, models.Response{
 			Message: "Invalid request",
@@ -62,18 +282,154 @@ func (h *userHandler) Login(c *gin.Context) {
 		return
 	}
 
-	token, err := h.userService.Login(c.Request.Context(), req.Username, req.Password)
+	codes, err := h.userService.ConfirmTOTP(c.Request.Context(), username.(string), req.Code)
 	if err != nil {
+		respondMFAError(c, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Two-factor authentication enabled",
+		Code:    http.StatusOK,
+		Data:    gin.H{"recovery_codes": codes},
+	})
+}
+
+// DisableTOTP turns two-factor authentication off
+func (h *userHandler) DisableTOTP(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
 		c.JSON(http.StatusUnauthorized, models.Response{
-			Message: "Invalid username or password",
+			Message: "Unauthorized",
+			Code:    http.StatusUnauthorized,
+		})
+		return
+	}
+
+	var req models.CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	if err := h.userService.DisableTOTP(c.Request.Context(), username.(string), req.Code, c.ClientIP()); err != nil {
+		respondMFAError(c, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.Response{
+		Message: "Two-factor authentication disabled",
+		Code:    http.StatusOK,
+	})
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes
+func (h *userHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	username, ok := c.Get("username")
+	if !ok {
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Unauthorized",
 			Code:    http.StatusUnauthorized,
 		})
 		return
 	}
 
+	var req models.CodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid request",
+			Code:    http.StatusBadRequest,
+		})
+		return
+	}
+
+	codes, err := h.userService.RegenerateRecoveryCodes(c.Request.Context(), username.(string), req.Code, c.ClientIP())
+	if err != nil {
+		respondMFAError(c, err, "Failed to regenerate recovery codes")
+		return
+	}
+
 	c.JSON(http.StatusOK, models.Response{
-		Message: "Login successful",
+		Message: "Success",
 		Code:    http.StatusOK,
-		Data:    token,
+		Data:    gin.H{"recovery_codes": codes},
+	})
+}
+
+func respondLoginError(c *gin.Context, err error) {
+	var locked *services.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.Response{
+			Message: "Too many failed login attempts, try again later",
+			Code:    http.StatusTooManyRequests,
+		})
+	case errors.Is(err, services.ErrAccountDisabled):
+		c.JSON(http.StatusForbidden, models.Response{
+			Message: "Account disabled",
+			Code:    http.StatusForbidden,
+		})
+	case errors.Is(err, services.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Invalid username or password",
+			Code:    http.StatusUnauthorized,
+		})
+	case errors.Is(err, services.ErrInvalidToken), errors.Is(err, services.ErrInvalidCode), errors.Is(err, services.ErrTOTPNotEnabled):
+		c.JSON(http.StatusUnauthorized, models.Response{
+			Message: "Invalid or expired two-factor code",
+			Code:    http.StatusUnauthorized,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: "Failed to login",
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
+
+func respondMFAError(c *gin.Context, err error, message string) {
+	var locked *services.LoginLockedError
+	switch {
+	case errors.As(err, &locked):
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(locked.RetryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, models.Response{
+			Message: "Too many failed attempts, try again later",
+			Code:    http.StatusTooManyRequests,
+		})
+	case errors.Is(err, services.ErrInvalidCode):
+		c.JSON(http.StatusBadRequest, models.Response{
+			Message: "Invalid two-factor code",
+			Code:    http.StatusBadRequest,
+		})
+	case errors.Is(err, services.ErrTOTPAlreadyEnabled), errors.Is(err, services.ErrTOTPNotEnabled):
+		c.JSON(http.StatusConflict, models.Response{
+			Message: err.Error(),
+			Code:    http.StatusConflict,
+		})
+	default:
+		c.JSON(http.StatusInternalServerError, models.Response{
+			Message: message,
+			Code:    http.StatusInternalServerError,
+		})
+	}
+}
+
+// respondValidationError writes a 400 listing every rejected field and
+// reports whether err was a validation error.
+func respondValidationError(c *gin.Context, err error) bool {
+	var validationErr *services.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+
+	c.JSON(http.StatusBadRequest, models.Response{
+		Message: "Validation failed",
+		Code:    http.StatusBadRequest,
+		Data:    gin.H{"errors": validationErr.Fields},
 	})
+	return true
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/nqvinh00/colorscheme/handlers"
 	"github.com/nqvinh00/colorscheme/handlers/middleware"
+	"github.com/nqvinh00/colorscheme/models"
 	"github.com/nqvinh00/colorscheme/pkg/database"
 	"github.com/nqvinh00/colorscheme/repository"
 	"github.com/nqvinh00/colorscheme/services"
@@ -16,6 +17,8 @@ import (
 	"github.com/nqvinh00/colorscheme/constant"
 	"github.com/nqvinh00/colorscheme/pkg/config"
 	"github.com/nqvinh00/colorscheme/pkg/log"
+	"github.com/nqvinh00/colorscheme/pkg/mailer"
+	"github.com/nqvinh00/colorscheme/pkg/policy"
 )
 
 var (
@@ -43,14 +46,40 @@ func main() {
 		log.Fatal().Err(err).Msg("Failed to connect to database")
 	}
 
+	mail, err := mailer.New(cfg.Mailer, cfg.Environment, log)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to initialize mailer")
+	}
+
+	registrationPolicy, err := policy.New(cfg.Policy)
+	if err != nil {
+		log.Fatal().Err(err).Msg("Failed to load registration policy")
+	}
+
 	userRepo := repository.NewUserRepository(db)
+	loginAttemptRepo := repository.NewLoginAttemptRepository(db)
 	colorSchemeRepo := repository.NewColorSchemeRepository(db)
-	userService := services.NewUserService(userRepo, log, cfg.JwtSecret)
-	colorSchemeService := services.NewColorSchemeService(colorSchemeRepo, log)
+	collectionRepo := repository.NewCollectionRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	commentRepo := repository.NewCommentRepository(db)
+	userService := services.NewUserService(userRepo, loginAttemptRepo, mail, registrationPolicy, log, cfg.JwtSecret, cfg.BaseURL, cfg.Login)
+	colorSchemeService := services.NewColorSchemeService(colorSchemeRepo, orgRepo, log)
+	collectionService := services.NewCollectionService(collectionRepo, colorSchemeRepo, orgRepo, log)
+	organizationService := services.NewOrganizationService(orgRepo, userRepo, colorSchemeRepo, log)
+	commentService := services.NewCommentService(commentRepo, colorSchemeRepo, orgRepo, log)
+	adminService := services.NewAdminService(userRepo, colorSchemeRepo, log)
 	userHandler := handlers.NewUserHandler(userService)
 	colorSchemeHandler := handlers.NewColorSchemeHandler(colorSchemeService)
+	collectionHandler := handlers.NewCollectionHandler(collectionService)
+	organizationHandler := handlers.NewOrganizationHandler(organizationService)
+	commentHandler := handlers.NewCommentHandler(commentService)
+	adminHandler := handlers.NewAdminHandler(adminService)
 
 	router := gin.New()
+	// Login throttling keys on the client IP, which must not be spoofable
+	if err := router.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatal().Err(err).Msg("Failed to set trusted proxies")
+	}
 	router.Use(gin.Recovery())
 
 	// Serve frontend static files
@@ -61,14 +90,83 @@ func main() {
 	{
 		api.POST("/register", userHandler.CreateAccount)
 		api.POST("/login", userHandler.Login)
+		api.POST("/login/mfa", userHandler.VerifyMFA)
+		api.POST("/verify-email", userHandler.VerifyEmail)
+		api.POST("/password-reset/request", userHandler.RequestPasswordReset)
+		api.POST("/password-reset/confirm", userHandler.ResetPassword)
 
-		secureApi := api.Group("/", middleware.AuthMiddleware(cfg.JwtSecret))
+		secureApi := api.Group("/", middleware.AuthMiddleware(cfg.JwtSecret, userService))
 		{
+			secureApi.PUT("/me/email", userHandler.UpdateEmail)
+			secureApi.POST("/me/2fa/enroll", userHandler.EnrollTOTP)
+			secureApi.POST("/me/2fa/confirm", userHandler.ConfirmTOTP)
+			secureApi.POST("/me/2fa/disable", userHandler.DisableTOTP)
+			secureApi.POST("/me/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes)
+			secureApi.GET("/me/starred", colorSchemeHandler.GetStarredColorSchemes)
+
 			secureApi.GET("/color-schemes", colorSchemeHandler.GetAllColorSchemesByAuthor)
+			secureApi.GET("/color-schemes/trending", colorSchemeHandler.GetTrendingColorSchemes)
 			secureApi.GET("/color-schemes/:id", colorSchemeHandler.GetColorSchemeById)
+			secureApi.GET("/color-schemes/:id/accessibility", colorSchemeHandler.GetAccessibilityReport)
+			secureApi.GET("/color-schemes/:id/cvd", colorSchemeHandler.SimulateColorVision)
+			secureApi.GET("/color-schemes/:id/similar", colorSchemeHandler.SimilarColorSchemes)
+			secureApi.GET("/color-schemes/:id/diff/:other", colorSchemeHandler.DiffColorSchemes)
+			secureApi.GET("/color-schemes/:id/xterm", colorSchemeHandler.MapToXterm)
+			secureApi.GET("/color-schemes/:id/revisions", colorSchemeHandler.ListRevisions)
+			secureApi.GET("/color-schemes/:id/revisions/:revision", colorSchemeHandler.GetRevision)
+			secureApi.GET("/color-schemes/:id/lineage", colorSchemeHandler.GetLineage)
+			secureApi.GET("/color-schemes/:id/export", colorSchemeHandler.ExportColorScheme)
+			secureApi.GET("/tags", colorSchemeHandler.ListTags)
+			secureApi.GET("/tags/:tag/color-schemes", colorSchemeHandler.GetColorSchemesByTag)
 			secureApi.POST("/color-schemes", colorSchemeHandler.CreateColorScheme)
+			secureApi.POST("/color-schemes/derive", colorSchemeHandler.DeriveColorScheme)
+			secureApi.POST("/color-schemes/generate", colorSchemeHandler.GenerateColorScheme)
+			secureApi.POST("/color-schemes/extract", colorSchemeHandler.ExtractColorScheme)
+			secureApi.POST("/color-schemes/:id/variant", colorSchemeHandler.CreateVariant)
+			secureApi.POST("/color-schemes/:id/fork", colorSchemeHandler.ForkColorScheme)
+			secureApi.POST("/color-schemes/:id/merge", colorSchemeHandler.MergeColorScheme)
+			secureApi.POST("/color-schemes/:id/revisions/:revision/restore", colorSchemeHandler.RestoreRevision)
 			secureApi.PUT("/color-schemes", colorSchemeHandler.UpdateColorScheme)
+			secureApi.PUT("/color-schemes/:id", colorSchemeHandler.UpdateColorScheme)
+			secureApi.PATCH("/color-schemes/:id", colorSchemeHandler.PatchColorScheme)
+			secureApi.PUT("/color-schemes/:id/star", colorSchemeHandler.StarColorScheme)
+			secureApi.PUT("/color-schemes/:id/organization", colorSchemeHandler.TransferColorScheme)
 			secureApi.DELETE("/color-schemes/:id", colorSchemeHandler.DeleteColorScheme)
+			secureApi.DELETE("/color-schemes/:id/star", colorSchemeHandler.UnstarColorScheme)
+
+			secureApi.GET("/color-schemes/:id/comments", commentHandler.ListComments)
+			secureApi.POST("/color-schemes/:id/comments", commentHandler.CreateComment)
+			secureApi.POST("/color-schemes/:id/comments/:comment/accept", commentHandler.AcceptSuggestion)
+			secureApi.POST("/color-schemes/:id/comments/:comment/reject", commentHandler.RejectSuggestion)
+			secureApi.DELETE("/color-schemes/:id/comments/:comment", commentHandler.DeleteComment)
+
+			secureApi.GET("/collections", collectionHandler.ListCollections)
+			secureApi.GET("/collections/:id", collectionHandler.GetCollection)
+			secureApi.GET("/collections/:id/export", collectionHandler.ExportCollection)
+			secureApi.POST("/collections", collectionHandler.CreateCollection)
+			secureApi.PUT("/collections/:id", collectionHandler.UpdateCollection)
+			secureApi.DELETE("/collections/:id", collectionHandler.DeleteCollection)
+
+			secureApi.GET("/orgs", organizationHandler.ListOrganizations)
+			secureApi.GET("/orgs/:org", organizationHandler.GetOrganization)
+			secureApi.GET("/orgs/:org/color-schemes", organizationHandler.GetOrganizationColorSchemes)
+			secureApi.POST("/orgs", organizationHandler.CreateOrganization)
+			secureApi.PUT("/orgs/:org/members/:username", organizationHandler.SetMember)
+			secureApi.DELETE("/orgs/:org", organizationHandler.DeleteOrganization)
+			secureApi.DELETE("/orgs/:org/members/:username", organizationHandler.RemoveMember)
+
+			staffApi := secureApi.Group("/admin", middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+			{
+				staffApi.GET("/users", adminHandler.ListUsers)
+				staffApi.PUT("/users/:username/disabled", adminHandler.SetUserDisabled)
+				staffApi.DELETE("/color-schemes/:id", adminHandler.DeleteColorScheme)
+			}
+
+			adminApi := secureApi.Group("/admin", middleware.RequireRole(models.RoleAdmin))
+			{
+				adminApi.PUT("/users/:username/role", adminHandler.SetUserRole)
+				adminApi.PUT("/color-schemes/:id/author", adminHandler.ReassignColorScheme)
+			}
 		}
 	}
 
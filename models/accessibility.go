@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// ContrastPair is the contrast of one scheme color against the background or
+// foreground.
+type ContrastPair struct {
+	Key     string  `json:"key"`
+	Color   string  `json:"color"`
+	Against string  `json:"against"`
+	Ratio   float64 `json:"ratio"`
+	APCA    float64 `json:"apca_lc"`
+	AALarge bool    `json:"aa_large"`
+	AA      bool    `json:"aa"`
+	AAA     bool    `json:"aaa"`
+	// Ignored pairs compare a color with itself, e.g. black against a
+	// background taken from black, and do not count towards the score.
+	Ignored bool `json:"ignored,omitempty"`
+}
+
+type AccessibilityReport struct {
+	SchemeID           string         `json:"scheme_id"`
+	Background         string         `json:"background"`
+	Foreground         string         `json:"foreground"`
+	ForegroundContrast float64        `json:"foreground_contrast"`
+	ForegroundAPCA     float64        `json:"foreground_apca_lc"`
+	Pairs              []ContrastPair `json:"pairs"`
+	// FailingAA lists ANSI colors below AA contrast against the background
+	FailingAA   []string  `json:"failing_aa"`
+	MinContrast float64   `json:"min_contrast"`
+	Score       float64   `json:"score"`
+	InvalidKeys []string  `json:"invalid_keys,omitempty"`
+	ComputedAt  time.Time `json:"computed_at"`
+}
@@ -0,0 +1,27 @@
+package models
+
+import "time"
+
+// Collection is a named, ordered list of schemes. Schemes is only filled
+// when a single collection is fetched and holds the schemes the viewer can
+// see, in collection order.
+type Collection struct {
+	ID          string        `json:"id"`
+	Name        string        `json:"name"`
+	Description string        `json:"description"`
+	Owner       string        `json:"owner"`
+	Public      bool          `json:"public"`
+	SchemeIDs   []string      `json:"scheme_ids"`
+	CreatedAt   time.Time     `json:"created_at"`
+	UpdatedAt   time.Time     `json:"updated_at"`
+	Schemes     []ColorScheme `json:"schemes,omitempty"`
+}
+
+// CollectionRequest creates or replaces a collection. SchemeIDs sets the
+// schemes and their order.
+type CollectionRequest struct {
+	Name        string   `json:"name" binding:"required"`
+	Description string   `json:"description"`
+	Public      bool     `json:"public"`
+	SchemeIDs   []string `json:"scheme_ids"`
+}
@@ -2,9 +2,126 @@ package models
 
 // ColorScheme represents a terminal color scheme
 type ColorScheme struct {
-	ID       string            `json:"id"`
-	Name     string            `json:"name"`
-	Author   string            `json:"author"`
-	Category string            `json:"category"`
-	Colors   map[string]string `json:"colors"`
+	ID                 string            `json:"id"`
+	Name               string            `json:"name"`
+	Author             string            `json:"author"`
+	Organization       *string           `json:"organization,omitempty"`
+	Category           string            `json:"category"`
+	Colors             map[string]string `json:"colors"`
+	Public             bool              `json:"public"`
+	Tags               []string          `json:"tags"`
+	Version            int               `json:"version"`
+	Stars              int               `json:"stars"`
+	VariantID          *string           `json:"variant_id,omitempty"`
+	DuplicateOf        *string           `json:"duplicate_of,omitempty"`
+	ForkedFrom         *string           `json:"forked_from,omitempty"`
+	ForkedRevision     *int              `json:"forked_revision,omitempty"`
+	AccessibilityScore *float64          `json:"accessibility_score,omitempty"`
+	Metrics            *SchemeMetrics    `json:"metrics,omitempty"`
+}
+
+// ANSIColorKeys are the 16 terminal colors of a scheme in ANSI index order.
+var ANSIColorKeys = []string{
+	"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white",
+	"brightBlack", "brightRed", "brightGreen", "brightYellow",
+	"brightBlue", "brightMagenta", "brightCyan", "brightWhite",
+}
+
+// AnyVersion skips the version check of a conditional write, as
+// "If-Match: *" does.
+const AnyVersion = 0
+
+// Optional keys overriding the default background and foreground.
+const (
+	BackgroundKey = "background"
+	ForegroundKey = "foreground"
+)
+
+// BackgroundSlot returns the key holding the background color. Schemes
+// without an explicit background use black, as the previewer does.
+func (s ColorScheme) BackgroundSlot() string {
+	if _, ok := s.Colors[BackgroundKey]; ok {
+		return BackgroundKey
+	}
+	return "black"
+}
+
+// ForegroundSlot returns the key holding the default text color, white
+// unless the scheme sets a foreground.
+func (s ColorScheme) ForegroundSlot() string {
+	if _, ok := s.Colors[ForegroundKey]; ok {
+		return ForegroundKey
+	}
+	return "white"
+}
+
+// ColorSchemeFilter narrows list queries. Nil fields are not applied.
+type ColorSchemeFilter struct {
+	MinAccessibility *float64
+	// Category matches case-insensitively, empty matches any
+	Category string
+	// Traits must all be present
+	Traits         []string
+	MinChroma      *float64
+	MaxChroma      *float64
+	MinTemperature *float64
+	MaxTemperature *float64
+	MinContrast    *float64
+	// Tags must all be present, normalized
+	Tags []string
+	// Limit caps the number of results when positive
+	Limit int
+}
+
+// DerivedColorScheme is a scheme completed from a partial palette. Derived
+// lists the color keys that were filled in.
+type DerivedColorScheme struct {
+	Scheme  ColorScheme `json:"scheme"`
+	Derived []string    `json:"derived"`
+}
+
+// GeneratedColorScheme is a scheme built from seed colors. Passing Seed and
+// Harmony back reproduces the same scheme.
+type GeneratedColorScheme struct {
+	Scheme  ColorScheme `json:"scheme"`
+	Harmony string      `json:"harmony"`
+	Seed    int64       `json:"seed"`
+}
+
+// ExtractedColorScheme is a scheme extracted from an image, with the
+// quantized colors of the image it was built from.
+type ExtractedColorScheme struct {
+	Scheme   ColorScheme        `json:"scheme"`
+	Clusters []ExtractedCluster `json:"clusters"`
+}
+
+// ExtractedCluster is one quantized image color and the share of the image
+// it covers.
+type ExtractedCluster struct {
+	Color  string  `json:"color"`
+	Weight float64 `json:"weight"`
+}
+
+// VersionConflict is returned with 412 when a write was based on a stale
+// version.
+type VersionConflict struct {
+	CurrentVersion int `json:"current_version"`
+}
+
+// LineageEntry is a scheme in the fork tree of another. Revision is the
+// revision of the parent the fork was taken from.
+type LineageEntry struct {
+	ID           string  `json:"id"`
+	Name         string  `json:"name"`
+	Author       string  `json:"author"`
+	Organization *string `json:"organization,omitempty"`
+	Public       bool    `json:"public"`
+	Revision     *int    `json:"revision,omitempty"`
+}
+
+// Lineage lists the ancestors of a scheme, its parent first, and its
+// direct forks.
+type Lineage struct {
+	Ancestors []LineageEntry `json:"ancestors"`
+	Forks     []LineageEntry `json:"forks"`
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+type CommentKind string
+
+const (
+	CommentKindComment    CommentKind = "comment"
+	CommentKindSuggestion CommentKind = "suggestion"
+)
+
+type SuggestionStatus string
+
+const (
+	SuggestionOpen     SuggestionStatus = "open"
+	SuggestionAccepted SuggestionStatus = "accepted"
+	SuggestionRejected SuggestionStatus = "rejected"
+)
+
+// Comment is a comment on a scheme or, with Kind suggestion, a proposal of
+// new values for some of its colors. Revision is the scheme revision an
+// accepted suggestion created. Deleted comments keep their place in the
+// thread without a body.
+type Comment struct {
+	ID         string            `json:"id"`
+	SchemeID   string            `json:"scheme_id"`
+	ParentID   *string           `json:"parent_id,omitempty"`
+	Author     string            `json:"author"`
+	Body       string            `json:"body"`
+	Kind       CommentKind       `json:"kind"`
+	Changes    map[string]string `json:"changes,omitempty"`
+	Status     SuggestionStatus  `json:"status,omitempty"`
+	ResolvedBy *string           `json:"resolved_by,omitempty"`
+	ResolvedAt *time.Time        `json:"resolved_at,omitempty"`
+	Revision   *int              `json:"revision,omitempty"`
+	Deleted    bool              `json:"deleted"`
+	CreatedAt  time.Time         `json:"created_at"`
+	Replies    []Comment         `json:"replies,omitempty"`
+}
+
+// CommentRequest posts a comment, a reply when ParentID is set, or a
+// suggestion when Changes is not empty.
+type CommentRequest struct {
+	Body     string            `json:"body"`
+	ParentID *string           `json:"parent_id"`
+	Changes  map[string]string `json:"changes"`
+}
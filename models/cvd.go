@@ -0,0 +1,18 @@
+package models
+
+// ConfusablePair is two scheme colors that are hard to tell apart under a
+// color vision deficiency.
+type ConfusablePair struct {
+	A               string  `json:"a"`
+	B               string  `json:"b"`
+	OriginalDeltaE  float64 `json:"original_delta_e"`
+	SimulatedDeltaE float64 `json:"simulated_delta_e"`
+}
+
+// CVDSimulation is a scheme as seen with a color vision deficiency.
+type CVDSimulation struct {
+	Deficiency      string           `json:"deficiency"`
+	Severity        float64          `json:"severity"`
+	Scheme          ColorScheme      `json:"scheme"`
+	ConfusablePairs []ConfusablePair `json:"confusable_pairs"`
+}
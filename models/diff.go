@@ -0,0 +1,43 @@
+package models
+
+// Kinds of ColorChange.
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+)
+
+// ColorChange is one slot that differs between two schemes. DeltaE is the
+// CIEDE2000 difference for changed slots whose colors both parse.
+type ColorChange struct {
+	Key    string   `json:"key"`
+	Change string   `json:"change"`
+	From   *string  `json:"from,omitempty"`
+	To     *string  `json:"to,omitempty"`
+	DeltaE *float64 `json:"delta_e,omitempty"`
+}
+
+type SchemeDiff struct {
+	From      string        `json:"from"`
+	To        string        `json:"to"`
+	Changes   []ColorChange `json:"changes"`
+	Unchanged int           `json:"unchanged"`
+}
+
+// MergeConflict is a slot changed differently on both sides since the base.
+// Nil values mean the slot is absent on that side.
+type MergeConflict struct {
+	Key        string  `json:"key"`
+	Base       *string `json:"base"`
+	Local      *string `json:"local"`
+	Upstream   *string `json:"upstream"`
+	Resolution string  `json:"resolution"`
+}
+
+// MergeResult is the scheme after a three-way merge. Applied lists the
+// upstream changes taken in.
+type MergeResult struct {
+	Scheme    ColorScheme     `json:"scheme"`
+	Applied   []string        `json:"applied"`
+	Conflicts []MergeConflict `json:"conflicts"`
+}
@@ -0,0 +1,8 @@
+package models
+
+// ExportFile is a scheme or collection written out for download.
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+type ThrottleScope string
+
+const (
+	ThrottleScopeUsername ThrottleScope = "username"
+	ThrottleScopeIP       ThrottleScope = "ip"
+)
+
+// LoginThrottle tracks consecutive failed logins for a username or an IP.
+type LoginThrottle struct {
+	Scope         ThrottleScope `json:"scope"`
+	Key           string        `json:"key"`
+	Failures      int           `json:"failures"`
+	LastFailureAt time.Time     `json:"last_failure_at"`
+	LockedUntil   *time.Time    `json:"locked_until,omitempty"`
+}
+
+// LoginAudit is an audit record of a single login attempt.
+type LoginAudit struct {
+	Username  string    `json:"username"`
+	IP        string    `json:"ip"`
+	Success   bool      `json:"success"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+// Traits assigned to schemes by classification.
+const (
+	TraitDark         = "dark"
+	TraitLight        = "light"
+	TraitHighContrast = "high-contrast"
+	TraitPastel       = "pastel"
+	TraitMonochrome   = "monochrome"
+	TraitWarm         = "warm"
+	TraitCool         = "cool"
+)
+
+// SchemeMetrics are computed from a scheme's colors on every write.
+// Temperature runs from -1 (cool) to 1 (warm).
+type SchemeMetrics struct {
+	BackgroundLuminance float64  `json:"background_luminance"`
+	ForegroundContrast  float64  `json:"foreground_contrast"`
+	AverageChroma       float64  `json:"average_chroma"`
+	Temperature         float64  `json:"temperature"`
+	Traits              []string `json:"traits"`
+}
@@ -0,0 +1,58 @@
+package models
+
+import "time"
+
+// OrgRole is a member's role in an organization. Viewers can see the
+// organization's private schemes, editors can also change them and owners
+// also manage members.
+type OrgRole string
+
+const (
+	OrgRoleOwner  OrgRole = "owner"
+	OrgRoleEditor OrgRole = "editor"
+	OrgRoleViewer OrgRole = "viewer"
+)
+
+func (r OrgRole) Valid() bool {
+	switch r {
+	case OrgRoleOwner, OrgRoleEditor, OrgRoleViewer:
+		return true
+	}
+	return false
+}
+
+// CanEdit reports whether the role may change the organization's schemes.
+func (r OrgRole) CanEdit() bool {
+	return r == OrgRoleOwner || r == OrgRoleEditor
+}
+
+// Organization owns schemes jointly for its members. Role is the caller's
+// role; Members is only filled when a single organization is fetched.
+type Organization struct {
+	Name        string      `json:"name"`
+	DisplayName string      `json:"display_name"`
+	CreatedAt   time.Time   `json:"created_at"`
+	Role        OrgRole     `json:"role,omitempty"`
+	Members     []OrgMember `json:"members,omitempty"`
+}
+
+type OrgMember struct {
+	Username string    `json:"username"`
+	Role     OrgRole   `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+type OrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	DisplayName string `json:"display_name"`
+}
+
+type OrgMemberRequest struct {
+	Role OrgRole `json:"role" binding:"required"`
+}
+
+// TransferRequest moves a scheme into an organization, or back to a
+// personal account when Organization is null.
+type TransferRequest struct {
+	Organization *string `json:"organization"`
+}
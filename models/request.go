@@ -3,4 +3,81 @@ package models
 type AuthRequest struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	Email    string `json:"email" binding:"omitempty,email"`
+}
+
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type TokenRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type PasswordResetRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type CodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+type RoleRequest struct {
+	Role Role `json:"role" binding:"required"`
+}
+
+type DisableRequest struct {
+	Disabled *bool `json:"disabled" binding:"required"`
+}
+
+type AuthorRequest struct {
+	Author string `json:"author" binding:"required"`
+}
+
+type DeriveRequest struct {
+	Name     string            `json:"name"`
+	Category string            `json:"category"`
+	Colors   map[string]string `json:"colors" binding:"required"`
+}
+
+type GenerateRequest struct {
+	Name    string   `json:"name"`
+	Seeds   []string `json:"seeds" binding:"required,min=1"`
+	Harmony string   `json:"harmony"`
+	Mode    string   `json:"mode"`
+	Seed    *int64   `json:"seed"`
+}
+
+// ExtractRequest holds the form fields sent alongside an uploaded image.
+type ExtractRequest struct {
+	Name     string `form:"name"`
+	Mode     string `form:"mode"`
+	Clusters int    `form:"clusters"`
+}
+
+// MergeRequest pulls the changes made to UpstreamID since Base into a
+// scheme. For a fork both default to its parent and the revision it was
+// forked or last merged from. Resolutions picks "local" or "upstream" per
+// conflicting key, conflicts without one keep the local value.
+type MergeRequest struct {
+	UpstreamID  string            `json:"upstream_id"`
+	Base        map[string]string `json:"base"`
+	Resolutions map[string]string `json:"resolutions"`
+	DryRun      bool              `json:"dry_run"`
+}
+
+// Content types accepted by PATCH /color-schemes/:id.
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+type ForkRequest struct {
+	Name string `json:"name"`
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Revision is an immutable snapshot of a scheme taken on every create and
+// update. Revisions are numbered from 1 per scheme.
+type Revision struct {
+	SchemeID  string            `json:"scheme_id"`
+	Revision  int               `json:"revision"`
+	Name      string            `json:"name"`
+	Category  string            `json:"category"`
+	Colors    map[string]string `json:"colors"`
+	Editor    string            `json:"editor"`
+	CreatedAt time.Time         `json:"created_at"`
+}
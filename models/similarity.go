@@ -0,0 +1,18 @@
+package models
+
+// SchemeFeatures are the CIELAB values of a scheme's comparable slots,
+// stored so similarity search doesn't parse every scheme on each query.
+// Slots holds L, a and b per key, BackgroundL is kept apart for indexing.
+type SchemeFeatures struct {
+	SchemeID    string                `json:"scheme_id"`
+	BackgroundL float64               `json:"background_l"`
+	Slots       map[string][3]float64 `json:"slots"`
+}
+
+// SimilarColorScheme is a search result with its mean CIEDE2000 distance
+// from the queried scheme.
+type SimilarColorScheme struct {
+	Scheme        ColorScheme `json:"scheme"`
+	Distance      float64     `json:"distance"`
+	NearDuplicate bool        `json:"near_duplicate"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// StarStatus is a user's star on a scheme and the scheme's star count.
+type StarStatus struct {
+	SchemeID string `json:"scheme_id"`
+	Starred  bool   `json:"starred"`
+	Stars    int    `json:"stars"`
+}
+
+// TrendingColorScheme is a public scheme ranked by its recent stars. Score
+// sums the stars received in the trending window, each weighing half as
+// much per half-life since it was given.
+type TrendingColorScheme struct {
+	Scheme ColorScheme `json:"scheme"`
+	Score  float64     `json:"score"`
+}
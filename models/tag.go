@@ -0,0 +1,7 @@
+package models
+
+// TagCount is a tag and the number of schemes carrying it.
+type TagCount struct {
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
@@ -2,9 +2,61 @@ package models
 
 import "time"
 
+type Role string
+
+const (
+	RoleUser      Role = "user"
+	RoleModerator Role = "moderator"
+	RoleAdmin     Role = "admin"
+)
+
+func (r Role) Valid() bool {
+	switch r {
+	case RoleUser, RoleModerator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
 type User struct {
-	Username  string    `json:"username"`
-	Password  string    `json:"password"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	Username      string    `json:"username"`
+	Password      string    `json:"-"`
+	Email         *string   `json:"email,omitempty"`
+	EmailVerified bool      `json:"email_verified"`
+	TOTPSecret    *string   `json:"-"`
+	TOTPEnabled   bool      `json:"totp_enabled"`
+	Role          Role      `json:"role"`
+	Disabled      bool      `json:"disabled"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type TokenPurpose string
+
+const (
+	TokenPurposeEmailVerification TokenPurpose = "email_verification"
+	TokenPurposePasswordReset     TokenPurpose = "password_reset"
+)
+
+// UserToken is a single-use token sent to a user by email. Only the hash of
+// the token is persisted.
+type UserToken struct {
+	TokenHash string       `json:"-"`
+	Username  string       `json:"username"`
+	Purpose   TokenPurpose `json:"purpose"`
+	Email     string       `json:"email"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// LoginResult carries either a session token or, for accounts with two-factor
+// authentication, a short-lived token to exchange for one with a valid code.
+type LoginResult struct {
+	Token       string `json:"token,omitempty"`
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type TOTPEnrollment struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
 }
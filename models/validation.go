@@ -0,0 +1,9 @@
+package models
+
+// FieldError describes why a single request field was rejected. Code is a
+// stable machine-readable identifier, Message is meant for display.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// XtermMapping is the closest fixed terminal color for one scheme color.
+// Xterm256 is taken from the color cube and grayscale ramp (16-255), whose
+// values terminals don't let themes change; ANSI16 and ANSI8 index the
+// default system colors.
+type XtermMapping struct {
+	Key           string  `json:"key"`
+	Color         string  `json:"color"`
+	Xterm256      int     `json:"xterm256"`
+	Xterm256Color string  `json:"xterm256_color"`
+	DeltaE        float64 `json:"delta_e"`
+	ANSI16        int     `json:"ansi16"`
+	ANSI8         int     `json:"ansi8"`
+}
+
+// XtermReport maps a scheme onto the xterm palettes. Palette256 is a full
+// 256-color table whose cube and grayscale ramp are interpolated between
+// the scheme's own colors.
+type XtermReport struct {
+	SchemeID   string         `json:"scheme_id"`
+	Mappings   []XtermMapping `json:"mappings"`
+	Palette256 []string       `json:"palette256"`
+}
@@ -0,0 +1,135 @@
+package color
+
+import (
+	"math"
+	"testing"
+)
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// assertRGB fails unless got is within tolerance of want on every channel.
+func assertRGB(t *testing.T, got, want RGB, tolerance float64) {
+	t.Helper()
+	if !near(got.R, want.R, tolerance) || !near(got.G, want.G, tolerance) || !near(got.B, want.B, tolerance) {
+		t.Errorf("got {%g %g %g}, want {%g %g %g}", got.R, got.G, got.B, want.R, want.G, want.B)
+	}
+}
+
+// testColors covers the primaries, secondaries, grays and a few arbitrary
+// colors for round trip tests.
+var testColors = []string{
+	"#000000", "#ffffff", "#808080", "#ff0000", "#00ff00", "#0000ff",
+	"#ffff00", "#00ffff", "#ff00ff", "#1e1e2e", "#f5e0dc", "#268bd2",
+}
+
+func TestLinearize(t *testing.T) {
+	tests := []struct {
+		srgb, linear float64
+	}{
+		{0, 0},
+		{1, 1},
+		// Linear segment below the 0.04045 knee
+		{0.04, 0.04 / 12.92},
+		{0.5, 0.214041},
+		{0.73536, 0.5},
+	}
+
+	for _, tt := range tests {
+		if got := linearize(tt.srgb); !near(got, tt.linear, 1e-5) {
+			t.Errorf("linearize(%v) = %v, want %v", tt.srgb, got, tt.linear)
+		}
+		if got := delinearize(tt.linear); !near(got, tt.srgb, 1e-5) {
+			t.Errorf("delinearize(%v) = %v, want %v", tt.linear, got, tt.srgb)
+		}
+	}
+}
+
+func TestLinearRoundTrip(t *testing.T) {
+	for _, s := range testColors {
+		c := MustParse(s)
+		assertRGB(t, c.Linear().SRGB(), c, 1e-12)
+	}
+}
+
+func TestHSL(t *testing.T) {
+	tests := []struct {
+		color string
+		hsl   HSL
+		hsv   HSV
+	}{
+		{"#ff0000", HSL{0, 1, 0.5}, HSV{0, 1, 1}},
+		{"#00ff00", HSL{120, 1, 0.5}, HSV{120, 1, 1}},
+		{"#0000ff", HSL{240, 1, 0.5}, HSV{240, 1, 1}},
+		{"#ffffff", HSL{0, 0, 1}, HSV{0, 0, 1}},
+		{"#000000", HSL{0, 0, 0}, HSV{0, 0, 0}},
+		{"#808000", HSL{60, 1, 128.0 / 255 / 2}, HSV{60, 1, 128.0 / 255}},
+	}
+
+	for _, tt := range tests {
+		c := MustParse(tt.color)
+		if got := c.HSL(); !near(got.H, tt.hsl.H, 1e-9) || !near(got.S, tt.hsl.S, 1e-9) || !near(got.L, tt.hsl.L, 1e-9) {
+			t.Errorf("%s.HSL() = %+v, want %+v", tt.color, got, tt.hsl)
+		}
+		if got := c.HSV(); !near(got.H, tt.hsv.H, 1e-9) || !near(got.S, tt.hsv.S, 1e-9) || !near(got.V, tt.hsv.V, 1e-9) {
+			t.Errorf("%s.HSV() = %+v, want %+v", tt.color, got, tt.hsv)
+		}
+		assertRGB(t, tt.hsl.RGB(), c, 1e-9)
+		assertRGB(t, tt.hsv.RGB(), c, 1e-9)
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"#ff8800", "#ff8800"},
+		{"#F80", "#ff8800"},
+		{"#f80c", "#ff8800"},
+		{"#123", "#112233"},
+		{"#ff8800cc", "#ff8800"},
+		{"  #FF8800 ", "#ff8800"},
+		{"rgb(255, 136, 0)", "#ff8800"},
+		{"rgba(255 136 0 / 0.5)", "#ff8800"},
+		{"rgb(100%, 0%, 50%)", "#ff0080"},
+		{"rgb(300, -5, 0)", "#ff0000"},
+		{"hsl(120, 100%, 50%)", "#00ff00"},
+		{"hsl(480deg 100% 25%)", "#008000"},
+		{"hsla(0, 0%, 100%, 0.1)", "#ffffff"},
+	}
+
+	for _, tt := range tests {
+		c, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := c.Hex(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	tests := []string{
+		"", "red", "#gggggg", "#12345", "rgb(1, 2)", "rgb(a, b, c)", "hsv(0, 0, 0)", "rgb(1, 2, 3",
+		// Alpha digits are validated even though alpha is ignored
+		"#ffffffzz", "#fffz",
+		// ParseFloat accepts these, colors must not
+		"rgb(nan, 0, 0)", "rgb(0, inf, 0)", "rgb(0, 0, -infinity)", "rgb(nan%, 0%, 0%)",
+		"hsl(inf, 50%, 50%)", "hsl(0, nan%, 50%)", "hsl(0deg 50% +inf%)",
+	}
+	for _, in := range tests {
+		if c, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", in, c)
+		}
+	}
+}
+
+func TestHexClamps(t *testing.T) {
+	if got := (RGB{1.2, -0.1, 0.5}).Hex(); got != "#ff0080" {
+		t.Errorf("got %s, want #ff0080", got)
+	}
+}
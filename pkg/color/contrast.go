@@ -0,0 +1,77 @@
+package color
+
+import "math"
+
+// WCAG 2.x contrast thresholds.
+const (
+	ContrastAALarge = 3.0
+	ContrastAA      = 4.5
+	ContrastAAA     = 7.0
+)
+
+// RelativeLuminance is the WCAG 2.x relative luminance of c, in [0, 1].
+func (c RGB) RelativeLuminance() float64 {
+	l := c.Clamp().Linear()
+	return 0.2126*l.R + 0.7152*l.G + 0.0722*l.B
+}
+
+// ContrastRatio is the WCAG 2.x contrast ratio between a and b, in [1, 21].
+// It is symmetric.
+func ContrastRatio(a, b RGB) float64 {
+	la, lb := a.RelativeLuminance(), b.RelativeLuminance()
+	if la < lb {
+		la, lb = lb, la
+	}
+	return (la + 0.05) / (lb + 0.05)
+}
+
+// APCA constants, version 0.0.98G-4g.
+const (
+	apcaBlackThreshold = 0.022
+	apcaBlackClamp     = 1.414
+	apcaDeltaYMin      = 0.0005
+	apcaNormBG         = 0.56
+	apcaNormTXT        = 0.57
+	apcaRevBG          = 0.65
+	apcaRevTXT         = 0.62
+	apcaScale          = 1.14
+	apcaLoClip         = 0.1
+	apcaLoOffset       = 0.027
+)
+
+// APCAContrast returns the APCA lightness contrast Lc of text on bg. Unlike
+// WCAG it is not symmetric: positive values are dark text on a light
+// background, negative values light text on a dark background. |Lc| ranges
+// roughly from 0 to 108.
+func APCAContrast(text, bg RGB) float64 {
+	yt := apcaLuminance(text)
+	yb := apcaLuminance(bg)
+	if math.Abs(yb-yt) < apcaDeltaYMin {
+		return 0
+	}
+
+	var lc float64
+	if yb > yt {
+		s := (math.Pow(yb, apcaNormBG) - math.Pow(yt, apcaNormTXT)) * apcaScale
+		if s >= apcaLoClip {
+			lc = s - apcaLoOffset
+		}
+	} else {
+		s := (math.Pow(yb, apcaRevBG) - math.Pow(yt, apcaRevTXT)) * apcaScale
+		if s <= -apcaLoClip {
+			lc = s + apcaLoOffset
+		}
+	}
+	return lc * 100
+}
+
+// apcaLuminance is APCA's screen luminance estimate with its soft clamp for
+// near-black colors.
+func apcaLuminance(c RGB) float64 {
+	c = c.Clamp()
+	y := 0.2126729*math.Pow(c.R, 2.4) + 0.7151522*math.Pow(c.G, 2.4) + 0.0721750*math.Pow(c.B, 2.4)
+	if y < apcaBlackThreshold {
+		y += math.Pow(apcaBlackThreshold-y, apcaBlackClamp)
+	}
+	return y
+}
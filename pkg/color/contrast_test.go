@@ -0,0 +1,66 @@
+package color
+
+import "testing"
+
+func TestRelativeLuminance(t *testing.T) {
+	tests := []struct {
+		color string
+		want  float64
+	}{
+		{"#000000", 0},
+		{"#ffffff", 1},
+		{"#ff0000", 0.2126},
+		{"#00ff00", 0.7152},
+		{"#0000ff", 0.0722},
+		{"#808080", 0.215861},
+	}
+
+	for _, tt := range tests {
+		if got := MustParse(tt.color).RelativeLuminance(); !near(got, tt.want, 1e-5) {
+			t.Errorf("%s: got %v, want %v", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestContrastRatio(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"#000000", "#ffffff", 21},
+		{"#ffffff", "#ffffff", 1},
+		{"#777777", "#ffffff", 4.478},
+		{"#767676", "#ffffff", 4.542},
+		{"#ff0000", "#ffffff", 3.998},
+		{"#0000ff", "#000000", 2.444},
+	}
+
+	for _, tt := range tests {
+		a, b := MustParse(tt.a), MustParse(tt.b)
+		if got := ContrastRatio(a, b); !near(got, tt.want, 1e-3) {
+			t.Errorf("ContrastRatio(%s, %s) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got, rev := ContrastRatio(a, b), ContrastRatio(b, a); got != rev {
+			t.Errorf("ContrastRatio(%s, %s) = %v but reversed %v", tt.a, tt.b, got, rev)
+		}
+	}
+}
+
+func TestAPCAContrast(t *testing.T) {
+	tests := []struct {
+		text, bg string
+		want     float64
+	}{
+		{"#000000", "#ffffff", 106.04},
+		{"#ffffff", "#000000", -107.88},
+		{"#888888", "#ffffff", 63.06},
+		{"#ffffff", "#888888", -68.54},
+		{"#123456", "#123456", 0},
+	}
+
+	for _, tt := range tests {
+		if got := APCAContrast(MustParse(tt.text), MustParse(tt.bg)); !near(got, tt.want, 0.01) {
+			t.Errorf("APCAContrast(%s, %s) = %v, want %v", tt.text, tt.bg, got, tt.want)
+		}
+	}
+}
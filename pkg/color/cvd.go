@@ -0,0 +1,71 @@
+package color
+
+type Deficiency string
+
+const (
+	Protanopia    Deficiency = "protanopia"
+	Deuteranopia  Deficiency = "deuteranopia"
+	Tritanopia    Deficiency = "tritanopia"
+	Achromatopsia Deficiency = "achromatopsia"
+)
+
+// Deficiencies lists every supported color vision deficiency.
+var Deficiencies = []Deficiency{Protanopia, Deuteranopia, Tritanopia, Achromatopsia}
+
+func (d Deficiency) Valid() bool {
+	switch d {
+	case Protanopia, Deuteranopia, Tritanopia, Achromatopsia:
+		return true
+	}
+	return false
+}
+
+// Machado, Oliveira and Fernandes (2009) simulation matrices at full
+// severity, applied in linear RGB.
+var cvdMatrices = map[Deficiency][3][3]float64{
+	Protanopia: {
+		{0.152286, 1.052583, -0.204868},
+		{0.114503, 0.786281, 0.099216},
+		{-0.003882, -0.048116, 1.051998},
+	},
+	Deuteranopia: {
+		{0.367322, 0.860646, -0.227968},
+		{0.280085, 0.672501, 0.047413},
+		{-0.011820, 0.042940, 0.968881},
+	},
+	Tritanopia: {
+		{1.255528, -0.076749, -0.178779},
+		{-0.078411, 0.930809, 0.147602},
+		{0.004733, 0.691367, 0.303900},
+	},
+}
+
+// SimulateCVD returns how c appears under deficiency d. severity in [0, 1]
+// blends between normal vision and full dichromacy (or, for achromatopsia,
+// full monochromacy).
+func SimulateCVD(c RGB, d Deficiency, severity float64) RGB {
+	severity = clamp01(severity)
+	lin := c.Clamp().Linear()
+
+	var sim LinearRGB
+	if d == Achromatopsia {
+		y := lin.XYZ().Y
+		sim = LinearRGB{y, y, y}
+	} else {
+		m, ok := cvdMatrices[d]
+		if !ok {
+			return c
+		}
+		sim = LinearRGB{
+			R: m[0][0]*lin.R + m[0][1]*lin.G + m[0][2]*lin.B,
+			G: m[1][0]*lin.R + m[1][1]*lin.G + m[1][2]*lin.B,
+			B: m[2][0]*lin.R + m[2][1]*lin.G + m[2][2]*lin.B,
+		}
+	}
+
+	return LinearRGB{
+		R: lin.R + (sim.R-lin.R)*severity,
+		G: lin.G + (sim.G-lin.G)*severity,
+		B: lin.B + (sim.B-lin.B)*severity,
+	}.SRGB().Clamp()
+}
@@ -0,0 +1,89 @@
+package color
+
+import "math"
+
+// DeltaE76 is the Euclidean distance in Lab.
+func DeltaE76(a, b Lab) float64 {
+	return math.Sqrt((a.L-b.L)*(a.L-b.L) + (a.A-b.A)*(a.A-b.A) + (a.B-b.B)*(a.B-b.B))
+}
+
+// DeltaE2000 is the CIEDE2000 color difference with unit weights. A value
+// around 2.3 is a just noticeable difference.
+func DeltaE2000(a, b Lab) float64 {
+	c1 := math.Hypot(a.A, a.B)
+	c2 := math.Hypot(b.A, b.B)
+	cBar7 := math.Pow((c1+c2)/2, 7)
+	g := 0.5 * (1 - math.Sqrt(cBar7/(cBar7+math.Pow(25, 7))))
+
+	a1 := (1 + g) * a.A
+	a2 := (1 + g) * b.A
+	c1p := math.Hypot(a1, a.B)
+	c2p := math.Hypot(a2, b.B)
+	h1p := hueAngle(a.B, a1)
+	h2p := hueAngle(b.B, a2)
+
+	dL := b.L - a.L
+	dC := c2p - c1p
+
+	var dh float64
+	switch {
+	case c1p*c2p == 0:
+		dh = 0
+	case math.Abs(h2p-h1p) <= 180:
+		dh = h2p - h1p
+	case h2p-h1p > 180:
+		dh = h2p - h1p - 360
+	default:
+		dh = h2p - h1p + 360
+	}
+	dH := 2 * math.Sqrt(c1p*c2p) * math.Sin(radians(dh/2))
+
+	lBar := (a.L + b.L) / 2
+	cBarP := (c1p + c2p) / 2
+
+	var hBar float64
+	switch {
+	case c1p*c2p == 0:
+		hBar = h1p + h2p
+	case math.Abs(h1p-h2p) <= 180:
+		hBar = (h1p + h2p) / 2
+	case h1p+h2p < 360:
+		hBar = (h1p + h2p + 360) / 2
+	default:
+		hBar = (h1p + h2p - 360) / 2
+	}
+
+	t := 1 - 0.17*math.Cos(radians(hBar-30)) +
+		0.24*math.Cos(radians(2*hBar)) +
+		0.32*math.Cos(radians(3*hBar+6)) -
+		0.20*math.Cos(radians(4*hBar-63))
+
+	dTheta := 30 * math.Exp(-math.Pow((hBar-275)/25, 2))
+	cBarP7 := math.Pow(cBarP, 7)
+	rc := 2 * math.Sqrt(cBarP7/(cBarP7+math.Pow(25, 7)))
+	lBar50 := (lBar - 50) * (lBar - 50)
+	sl := 1 + 0.015*lBar50/math.Sqrt(20+lBar50)
+	sc := 1 + 0.045*cBarP
+	sh := 1 + 0.015*cBarP*t
+	rt := -math.Sin(radians(2*dTheta)) * rc
+
+	return math.Sqrt(
+		(dL/sl)*(dL/sl) +
+			(dC/sc)*(dC/sc) +
+			(dH/sh)*(dH/sh) +
+			rt*(dC/sc)*(dH/sh),
+	)
+}
+
+// DeltaEOK is the Euclidean distance in OKLab, scaled by 100 to be roughly
+// comparable with the Lab based metrics.
+func DeltaEOK(a, b OKLab) float64 {
+	return 100 * math.Sqrt((a.L-b.L)*(a.L-b.L)+(a.A-b.A)*(a.A-b.A)+(a.B-b.B)*(a.B-b.B))
+}
+
+func hueAngle(b, a float64) float64 {
+	if a == 0 && b == 0 {
+		return 0
+	}
+	return degrees(math.Atan2(b, a))
+}
@@ -0,0 +1,74 @@
+package color
+
+import "math"
+
+func (c RGB) HSL() HSL {
+	max := math.Max(c.R, math.Max(c.G, c.B))
+	min := math.Min(c.R, math.Min(c.G, c.B))
+	l := (max + min) / 2
+	d := max - min
+	if d == 0 {
+		return HSL{0, 0, l}
+	}
+
+	s := d / (1 - math.Abs(2*l-1))
+	return HSL{hue(c, max, d), s, l}
+}
+
+func (h HSL) RGB() RGB {
+	c := (1 - math.Abs(2*h.L-1)) * h.S
+	return fromChroma(h.H, c, h.L-c/2)
+}
+
+func (c RGB) HSV() HSV {
+	max := math.Max(c.R, math.Max(c.G, c.B))
+	min := math.Min(c.R, math.Min(c.G, c.B))
+	d := max - min
+	if d == 0 {
+		return HSV{0, 0, max}
+	}
+
+	return HSV{hue(c, max, d), d / max, max}
+}
+
+func (h HSV) RGB() RGB {
+	c := h.V * h.S
+	return fromChroma(h.H, c, h.V-c)
+}
+
+// hue computes the shared HSL/HSV hue from the largest channel and chroma d.
+func hue(c RGB, max, d float64) float64 {
+	var h float64
+	switch max {
+	case c.R:
+		h = math.Mod((c.G-c.B)/d, 6)
+	case c.G:
+		h = (c.B-c.R)/d + 2
+	default:
+		h = (c.R-c.G)/d + 4
+	}
+	return normalizeHue(h * 60)
+}
+
+// fromChroma builds an RGB from hue, chroma c and the lightness offset m.
+func fromChroma(h, c, m float64) RGB {
+	hp := normalizeHue(h) / 60
+	x := c * (1 - math.Abs(math.Mod(hp, 2)-1))
+
+	var r, g, b float64
+	switch {
+	case hp < 1:
+		r, g, b = c, x, 0
+	case hp < 2:
+		r, g, b = x, c, 0
+	case hp < 3:
+		r, g, b = 0, c, x
+	case hp < 4:
+		r, g, b = 0, x, c
+	case hp < 5:
+		r, g, b = x, 0, c
+	default:
+		r, g, b = c, 0, x
+	}
+	return RGB{r + m, g + m, b + m}
+}
@@ -0,0 +1,101 @@
+package color
+
+import "math"
+
+// D65 reference white.
+var whiteD65 = XYZ{0.95047, 1.0, 1.08883}
+
+const (
+	labEpsilon = 216.0 / 24389.0
+	labKappa   = 24389.0 / 27.0
+)
+
+func (c LinearRGB) XYZ() XYZ {
+	return XYZ{
+		X: 0.4124564*c.R + 0.3575761*c.G + 0.1804375*c.B,
+		Y: 0.2126729*c.R + 0.7151522*c.G + 0.0721750*c.B,
+		Z: 0.0193339*c.R + 0.1191920*c.G + 0.9503041*c.B,
+	}
+}
+
+func (x XYZ) LinearRGB() LinearRGB {
+	return LinearRGB{
+		R: 3.2404542*x.X - 1.5371385*x.Y - 0.4985314*x.Z,
+		G: -0.9692660*x.X + 1.8760108*x.Y + 0.0415560*x.Z,
+		B: 0.0556434*x.X - 0.2040259*x.Y + 1.0572252*x.Z,
+	}
+}
+
+func (c RGB) XYZ() XYZ {
+	return c.Linear().XYZ()
+}
+
+func (x XYZ) RGB() RGB {
+	return x.LinearRGB().SRGB()
+}
+
+func (x XYZ) Lab() Lab {
+	fx := labF(x.X / whiteD65.X)
+	fy := labF(x.Y / whiteD65.Y)
+	fz := labF(x.Z / whiteD65.Z)
+	return Lab{
+		L: 116*fy - 16,
+		A: 500 * (fx - fy),
+		B: 200 * (fy - fz),
+	}
+}
+
+func (l Lab) XYZ() XYZ {
+	fy := (l.L + 16) / 116
+	fx := fy + l.A/500
+	fz := fy - l.B/200
+	return XYZ{
+		X: whiteD65.X * labFInv(fx),
+		Y: whiteD65.Y * labFInv(fy),
+		Z: whiteD65.Z * labFInv(fz),
+	}
+}
+
+func (c RGB) Lab() Lab {
+	return c.XYZ().Lab()
+}
+
+// RGB converts back to sRGB without clamping, check InGamut if needed.
+func (l Lab) RGB() RGB {
+	return l.XYZ().RGB()
+}
+
+func (l Lab) LCh() LCh {
+	return LCh{
+		L: l.L,
+		C: math.Hypot(l.A, l.B),
+		H: degrees(math.Atan2(l.B, l.A)),
+	}
+}
+
+func (l LCh) Lab() Lab {
+	h := radians(l.H)
+	return Lab{l.L, l.C * math.Cos(h), l.C * math.Sin(h)}
+}
+
+func (c RGB) LCh() LCh {
+	return c.Lab().LCh()
+}
+
+func (l LCh) RGB() RGB {
+	return l.Lab().RGB()
+}
+
+func labF(t float64) float64 {
+	if t > labEpsilon {
+		return math.Cbrt(t)
+	}
+	return (labKappa*t + 16) / 116
+}
+
+func labFInv(t float64) float64 {
+	if t3 := t * t * t; t3 > labEpsilon {
+		return t3
+	}
+	return (116*t - 16) / labKappa
+}
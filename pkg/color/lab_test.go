@@ -0,0 +1,89 @@
+package color
+
+import "testing"
+
+func assertLab(t *testing.T, name string, got, want Lab, tolerance float64) {
+	t.Helper()
+	if !near(got.L, want.L, tolerance) || !near(got.A, want.A, tolerance) || !near(got.B, want.B, tolerance) {
+		t.Errorf("%s: got %+v, want %+v", name, got, want)
+	}
+}
+
+func TestLab(t *testing.T) {
+	tests := []struct {
+		color string
+		want  Lab
+	}{
+		{"#000000", Lab{0, 0, 0}},
+		{"#ffffff", Lab{100, 0, 0}},
+		{"#ff0000", Lab{53.2408, 80.0925, 67.2032}},
+		{"#00ff00", Lab{87.7347, -86.1827, 83.1793}},
+		{"#0000ff", Lab{32.2970, 79.1875, -107.8602}},
+		{"#808080", Lab{53.5850, 0, 0}},
+	}
+
+	for _, tt := range tests {
+		assertLab(t, tt.color, MustParse(tt.color).Lab(), tt.want, 1e-2)
+	}
+}
+
+// The published matrices have seven digits, so round trips drift by about
+// 1e-6, well under an 8-bit step.
+func TestLabRoundTrip(t *testing.T) {
+	for _, s := range testColors {
+		c := MustParse(s)
+		assertRGB(t, c.Lab().RGB(), c, 1e-5)
+		assertRGB(t, c.LCh().RGB(), c, 1e-5)
+	}
+}
+
+func TestLCh(t *testing.T) {
+	lch := MustParse("#ff0000").LCh()
+	if !near(lch.L, 53.2408, 1e-2) || !near(lch.C, 104.5518, 1e-2) || !near(lch.H, 39.9990, 1e-2) {
+		t.Errorf("got %+v", lch)
+	}
+
+	// Hues are wrapped into [0, 360)
+	if h := MustParse("#0000ff").LCh().H; h < 0 || h >= 360 {
+		t.Errorf("hue %v out of range", h)
+	}
+}
+
+// Pairs from Sharma, Wu and Dalal, "The CIEDE2000 Color-Difference
+// Formula: Implementation Notes, Supplementary Test Data, and Mathematical
+// Observations".
+func TestDeltaE2000(t *testing.T) {
+	tests := []struct {
+		a, b Lab
+		want float64
+	}{
+		{Lab{50, 2.6772, -79.7751}, Lab{50, 0, -82.7485}, 2.0425},
+		{Lab{50, 3.1571, -77.2803}, Lab{50, 0, -82.7485}, 2.8615},
+		{Lab{50, 2.8361, -74.0200}, Lab{50, 0, -82.7485}, 3.4412},
+		{Lab{50, -1.3802, -84.2814}, Lab{50, 0, -82.7485}, 1.0000},
+		{Lab{50, 0, 0}, Lab{50, -1, 2}, 2.3669},
+		{Lab{50, -1, 2}, Lab{50, 0, 0}, 2.3669},
+		{Lab{50, 2.49, -0.001}, Lab{50, -2.49, 0.0009}, 7.1792},
+		{Lab{50, 2.49, -0.001}, Lab{50, -2.49, 0.0011}, 7.2195},
+		{Lab{50, -0.001, 2.49}, Lab{50, 0.0009, -2.49}, 4.8045},
+		{Lab{50, 2.5, 0}, Lab{73, 25, -18}, 27.1492},
+		{Lab{50, 2.5, 0}, Lab{61, -5, 29}, 22.8977},
+		{Lab{50, 2.5, 0}, Lab{56, -27, -3}, 31.9030},
+		{Lab{60.2574, -34.0099, 36.2677}, Lab{60.4626, -34.1751, 39.4387}, 1.2644},
+		{Lab{22.7233, 20.0904, -46.6940}, Lab{23.0331, 14.9730, -42.5619}, 2.0373},
+		{Lab{90.8027, -2.0831, 1.4410}, Lab{91.1528, -1.6435, 0.0447}, 1.4441},
+		{Lab{2.0776, 0.0795, -1.1350}, Lab{0.9033, -0.0636, -0.5514}, 0.9082},
+	}
+
+	for _, tt := range tests {
+		if got := DeltaE2000(tt.a, tt.b); !near(got, tt.want, 1e-4) {
+			t.Errorf("DeltaE2000(%+v, %+v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDeltaE76(t *testing.T) {
+	if got := DeltaE76(Lab{50, 0, 0}, Lab{53, 4, 0}); !near(got, 5, 1e-12) {
+		t.Errorf("got %v, want 5", got)
+	}
+}
@@ -0,0 +1,87 @@
+package color
+
+import "math"
+
+func (c LinearRGB) OKLab() OKLab {
+	l := math.Cbrt(0.4122214708*c.R + 0.5363325363*c.G + 0.0514459929*c.B)
+	m := math.Cbrt(0.2119034982*c.R + 0.6806995451*c.G + 0.1073969566*c.B)
+	s := math.Cbrt(0.0883024619*c.R + 0.2817188376*c.G + 0.6299787005*c.B)
+
+	return OKLab{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+func (o OKLab) LinearRGB() LinearRGB {
+	l := o.L + 0.3963377774*o.A + 0.2158037573*o.B
+	m := o.L - 0.1055613458*o.A - 0.0638541728*o.B
+	s := o.L - 0.0894841775*o.A - 1.2914855480*o.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+
+	return LinearRGB{
+		R: 4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		G: -1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		B: -0.0041960863*l - 0.7034186147*m + 1.7076147010*s,
+	}
+}
+
+func (c RGB) OKLab() OKLab {
+	return c.Linear().OKLab()
+}
+
+// RGB converts back to sRGB without clamping, check InGamut if needed.
+func (o OKLab) RGB() RGB {
+	return o.LinearRGB().SRGB()
+}
+
+func (o OKLab) OKLCH() OKLCH {
+	return OKLCH{
+		L: o.L,
+		C: math.Hypot(o.A, o.B),
+		H: degrees(math.Atan2(o.B, o.A)),
+	}
+}
+
+func (o OKLCH) OKLab() OKLab {
+	h := radians(o.H)
+	return OKLab{o.L, o.C * math.Cos(h), o.C * math.Sin(h)}
+}
+
+func (c RGB) OKLCH() OKLCH {
+	return c.OKLab().OKLCH()
+}
+
+// RGB converts back to sRGB without clamping, use ClipToGamut for colors
+// that may fall outside sRGB.
+func (o OKLCH) RGB() RGB {
+	return o.OKLab().RGB()
+}
+
+// ClipToGamut maps o into sRGB by reducing chroma at constant lightness and
+// hue, the approach CSS Color 4 recommends. Lightness itself is clamped to
+// [0, 1] first.
+func (o OKLCH) ClipToGamut() RGB {
+	o.L = clamp01(o.L)
+	o.H = normalizeHue(o.H)
+	if o.L == 0 || o.L == 1 {
+		return OKLCH{L: o.L}.RGB().Clamp()
+	}
+
+	if rgb := o.RGB(); rgb.InGamut() {
+		return rgb.Clamp()
+	}
+
+	// Binary search the largest in-gamut chroma
+	lo, hi := 0.0, o.C
+	for hi-lo > 1e-4 {
+		mid := (lo + hi) / 2
+		if (OKLCH{o.L, mid, o.H}).RGB().InGamut() {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	return OKLCH{o.L, lo, o.H}.RGB().Clamp()
+}
@@ -0,0 +1,73 @@
+package color
+
+import "testing"
+
+func TestOKLab(t *testing.T) {
+	tests := []struct {
+		color string
+		want  OKLab
+	}{
+		{"#000000", OKLab{0, 0, 0}},
+		{"#ffffff", OKLab{1, 0, 0}},
+		{"#ff0000", OKLab{0.627955, 0.224863, 0.125846}},
+		{"#00ff00", OKLab{0.866440, -0.233888, 0.179498}},
+		{"#0000ff", OKLab{0.452014, -0.032457, -0.311528}},
+	}
+
+	for _, tt := range tests {
+		got := MustParse(tt.color).OKLab()
+		if !near(got.L, tt.want.L, 1e-4) || !near(got.A, tt.want.A, 1e-4) || !near(got.B, tt.want.B, 1e-4) {
+			t.Errorf("%s: got %+v, want %+v", tt.color, got, tt.want)
+		}
+	}
+}
+
+func TestOKLabRoundTrip(t *testing.T) {
+	for _, s := range testColors {
+		c := MustParse(s)
+		assertRGB(t, c.OKLab().RGB(), c, 1e-5)
+		assertRGB(t, c.OKLCH().RGB(), c, 1e-5)
+	}
+}
+
+func TestClipToGamut(t *testing.T) {
+	tests := []OKLCH{
+		// Far outside sRGB at several hues and lightnesses
+		{0.7, 0.4, 150},
+		{0.5, 0.5, 30},
+		{0.9, 0.3, 270},
+		{0.2, 0.3, 330},
+	}
+
+	for _, o := range tests {
+		if o.RGB().InGamut() {
+			t.Fatalf("%+v is in gamut, pick a wider chroma", o)
+		}
+		got := o.ClipToGamut()
+		if !got.InGamut() {
+			t.Errorf("%+v clipped to %+v, still out of gamut", o, got)
+			continue
+		}
+		// Chroma is reduced, lightness and hue stay put
+		clipped := got.OKLCH()
+		if !near(clipped.L, o.L, 1e-3) || !near(clipped.H, o.H, 0.5) || clipped.C > o.C {
+			t.Errorf("%+v clipped to %+v", o, clipped)
+		}
+	}
+}
+
+// sRGB corners such as yellow come back from OKLCH a hair outside the gamut
+// and must not lose chroma for it.
+func TestClipToGamutKeepsInGamutColors(t *testing.T) {
+	for _, s := range testColors {
+		c := MustParse(s)
+		if got := c.OKLCH().ClipToGamut(); got.Hex() != s {
+			t.Errorf("%s clipped to %s", s, got.Hex())
+		}
+	}
+}
+
+func TestClipToGamutLightnessBounds(t *testing.T) {
+	assertRGB(t, OKLCH{1.5, 0.2, 40}.ClipToGamut(), RGB{1, 1, 1}, 1e-6)
+	assertRGB(t, OKLCH{-0.5, 0.2, 40}.ClipToGamut(), RGB{0, 0, 0}, 1e-6)
+}
@@ -0,0 +1,144 @@
+package color
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Parse reads the color formats accepted in scheme colors: #rgb, #rgba,
+// #rrggbb, #rrggbbaa (alpha is ignored), rgb()/rgba() with 0-255 or
+// percentage channels, and hsl()/hsla().
+func Parse(s string) (RGB, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+
+	switch {
+	case strings.HasPrefix(s, "#"):
+		return parseHex(s[1:])
+	case strings.HasPrefix(s, "rgb"):
+		return parseRGBFunc(s)
+	case strings.HasPrefix(s, "hsl"):
+		return parseHSLFunc(s)
+	}
+
+	return RGB{}, fmt.Errorf("unsupported color %q", s)
+}
+
+// MustParse is like Parse but panics on error. Meant for constants.
+func MustParse(s string) RGB {
+	c, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func parseHex(h string) (RGB, error) {
+	if len(h) != 3 && len(h) != 4 && len(h) != 6 && len(h) != 8 {
+		return RGB{}, fmt.Errorf("invalid hex color %q", "#"+h)
+	}
+
+	// Alpha digits are checked too, even though they are dropped
+	v, err := strconv.ParseUint(h, 16, 32)
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid hex color %q", "#"+h)
+	}
+
+	switch len(h) {
+	case 3:
+		v = expandNibbles(v)
+	case 4:
+		v = expandNibbles(v >> 4)
+	case 8:
+		v >>= 8
+	}
+	return FromRGB8(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+}
+
+// expandNibbles turns 0xrgb into 0xrrggbb.
+func expandNibbles(v uint64) uint64 {
+	r, g, b := v>>8&0xf, v>>4&0xf, v&0xf
+	return r*0x110000 | g*0x1100 | b*0x11
+}
+
+func parseRGBFunc(s string) (RGB, error) {
+	args, err := funcArgs(s, "rgb", "rgba")
+	if err != nil {
+		return RGB{}, err
+	}
+
+	var ch [3]float64
+	for i := range ch {
+		if strings.HasSuffix(args[i], "%") {
+			v, err := parseNumber(strings.TrimSuffix(args[i], "%"))
+			if err != nil {
+				return RGB{}, fmt.Errorf("invalid color %q", s)
+			}
+			ch[i] = v / 100
+			continue
+		}
+		v, err := parseNumber(args[i])
+		if err != nil {
+			return RGB{}, fmt.Errorf("invalid color %q", s)
+		}
+		ch[i] = v / 255
+	}
+	return RGB{ch[0], ch[1], ch[2]}.Clamp(), nil
+}
+
+func parseHSLFunc(s string) (RGB, error) {
+	args, err := funcArgs(s, "hsl", "hsla")
+	if err != nil {
+		return RGB{}, err
+	}
+
+	h, err := parseNumber(strings.TrimSuffix(args[0], "deg"))
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	sat, err := parseNumber(strings.TrimSuffix(args[1], "%"))
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	l, err := parseNumber(strings.TrimSuffix(args[2], "%"))
+	if err != nil {
+		return RGB{}, fmt.Errorf("invalid color %q", s)
+	}
+	return HSL{normalizeHue(h), clamp01(sat / 100), clamp01(l / 100)}.RGB(), nil
+}
+
+// parseNumber is strconv.ParseFloat without the NaN and infinities it also
+// accepts, which would otherwise survive Clamp.
+func parseNumber(s string) (float64, error) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, err
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, fmt.Errorf("%q is not a finite number", s)
+	}
+	return v, nil
+}
+
+// funcArgs splits "name(a, b, c[, alpha])" or the space separated CSS 4
+// form "name(a b c[ / alpha])" into its first three arguments.
+func funcArgs(s string, names ...string) ([]string, error) {
+	open := strings.IndexByte(s, '(')
+	if open < 0 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+
+	if !slices.Contains(names, strings.TrimSpace(s[:open])) {
+		return nil, fmt.Errorf("unsupported color %q", s)
+	}
+
+	body := s[open+1 : len(s)-1]
+	body = strings.NewReplacer(",", " ", "/", " ").Replace(body)
+	args := strings.Fields(body)
+	if len(args) != 3 && len(args) != 4 {
+		return nil, fmt.Errorf("invalid color %q", s)
+	}
+	return args[:3], nil
+}
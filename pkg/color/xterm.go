@@ -0,0 +1,54 @@
+package color
+
+// Xterm256 is the default xterm 256-color palette: the 16 system colors,
+// the 6x6x6 color cube from index 16 and the 24-step grayscale ramp from
+// index 232.
+var Xterm256 = func() [256]RGB {
+	var p [256]RGB
+	system := [16]uint32{
+		0x000000, 0xcd0000, 0x00cd00, 0xcdcd00, 0x0000ee, 0xcd00cd, 0x00cdcd, 0xe5e5e5,
+		0x7f7f7f, 0xff0000, 0x00ff00, 0xffff00, 0x5c5cff, 0xff00ff, 0x00ffff, 0xffffff,
+	}
+	for i, v := range system {
+		p[i] = FromRGB8(uint8(v>>16), uint8(v>>8), uint8(v))
+	}
+
+	levels := [6]uint8{0, 95, 135, 175, 215, 255}
+	for i := range 216 {
+		p[16+i] = FromRGB8(levels[i/36], levels[i/6%6], levels[i%6])
+	}
+
+	for i := range 24 {
+		v := uint8(8 + 10*i)
+		p[232+i] = FromRGB8(v, v, v)
+	}
+	return p
+}()
+
+var xtermLab = func() [256]Lab {
+	var p [256]Lab
+	for i, c := range Xterm256 {
+		p[i] = c.Lab()
+	}
+	return p
+}()
+
+// Ranges of the xterm palette.
+const (
+	XtermCubeStart = 16
+	XtermGrayStart = 232
+)
+
+// NearestXterm returns the index in [from, to) of the xterm color closest
+// to c by CIEDE2000, and the distance.
+func NearestXterm(c RGB, from, to int) (int, float64) {
+	lab := c.Lab()
+	best, bestDist := from, -1.0
+	for i := from; i < to; i++ {
+		d := DeltaE2000(lab, xtermLab[i])
+		if bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best, bestDist
+}
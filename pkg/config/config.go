@@ -10,8 +10,16 @@ import (
 type Config struct {
 	Environment constant.Environment `json:"environment" yaml:"environment"`
 	Port        string               `json:"port" yaml:"port"`
+	BaseURL     string               `json:"base_url" yaml:"base_url"`
 	DB          DBConfig             `json:"db" yaml:"db"`
 	JwtSecret   string               `json:"jwt_secret" yaml:"jwt_secret"`
+	Mailer      MailerConfig         `json:"mailer" yaml:"mailer"`
+	Login       LoginConfig          `json:"login" yaml:"login"`
+	Policy      PolicyConfig         `json:"policy" yaml:"policy"`
+	// TrustedProxies lists the proxy addresses or CIDRs whose
+	// X-Forwarded-For is believed when resolving client IPs. Empty trusts
+	// none, so the client IP is the connection's.
+	TrustedProxies []string `json:"trusted_proxies" yaml:"trusted_proxies"`
 }
 
 type DBConfig struct {
@@ -26,6 +34,42 @@ type DBConfig struct {
 	ConnMaxLifetime int    `json:"conn_max_lifetime" yaml:"conn_max_lifetime"`
 }
 
+// MailerConfig selects how outgoing mail is delivered. Driver is one of
+// "smtp", "file" or "log"; an empty driver falls back to "log".
+type MailerConfig struct {
+	Driver   string `json:"driver" yaml:"driver"`
+	From     string `json:"from" yaml:"from"`
+	Host     string `json:"host" yaml:"host"`
+	Port     int    `json:"port" yaml:"port"`
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Dir      string `json:"dir" yaml:"dir"`
+}
+
+// LoginConfig controls brute-force protection on login. Once a username or
+// IP exceeds its free attempts, every further attempt locks it out for
+// BaseDelaySeconds doubled per attempt, capped at MaxLockoutMinutes.
+// Attempts are counted up front and given back unless they fail. Counters
+// reset after ResetAfterMinutes without a failure.
+type LoginConfig struct {
+	UserFreeAttempts  int `json:"user_free_attempts" yaml:"user_free_attempts"`
+	IPFreeAttempts    int `json:"ip_free_attempts" yaml:"ip_free_attempts"`
+	BaseDelaySeconds  int `json:"base_delay_seconds" yaml:"base_delay_seconds"`
+	MaxLockoutMinutes int `json:"max_lockout_minutes" yaml:"max_lockout_minutes"`
+	ResetAfterMinutes int `json:"reset_after_minutes" yaml:"reset_after_minutes"`
+}
+
+// PolicyConfig holds the username and password rules enforced at
+// registration. Zero values fall back to the defaults in pkg/policy.
+type PolicyConfig struct {
+	UsernameMinLength   int      `json:"username_min_length" yaml:"username_min_length"`
+	UsernameMaxLength   int      `json:"username_max_length" yaml:"username_max_length"`
+	UsernamePattern     string   `json:"username_pattern" yaml:"username_pattern"`
+	ReservedUsernames   []string `json:"reserved_usernames" yaml:"reserved_usernames"`
+	PasswordMinLength   int      `json:"password_min_length" yaml:"password_min_length"`
+	CommonPasswordsFile string   `json:"common_passwords_file" yaml:"common_passwords_file"`
+}
+
 func LoadConfig(path string) (*Config, error) {
 	var config Config
 
@@ -0,0 +1,118 @@
+package palette
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// foregroundWeight makes default text count as much as two ANSI colors in
+// the score.
+const foregroundWeight = 2
+
+// Accessibility computes WCAG 2.x contrast ratios and APCA Lc values for
+// every ANSI color against the scheme's background and foreground.
+//
+// The score runs from 0 to 100 and is a weighted average over the ANSI
+// colors and the foreground, all measured against the background: AAA earns
+// full marks, AA 80%, large-text AA 50% and lower ratios scale down to 0.
+func Accessibility(scheme models.ColorScheme) (*models.AccessibilityReport, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return nil, err
+	}
+	// A NaN channel would poison every ratio and the score, and NaN can't
+	// be sent as JSON
+	if !finite(bg) || !finite(fg) {
+		return nil, errors.New("background or foreground is not a finite color")
+	}
+
+	bgSlot, fgSlot := scheme.BackgroundSlot(), scheme.ForegroundSlot()
+	report := &models.AccessibilityReport{
+		SchemeID:           scheme.ID,
+		Background:         bg.Hex(),
+		Foreground:         fg.Hex(),
+		ForegroundContrast: round(color.ContrastRatio(fg, bg), 2),
+		ForegroundAPCA:     round(color.APCAContrast(fg, bg), 1),
+		Pairs:              []models.ContrastPair{},
+		FailingAA:          []string{},
+		MinContrast:        math.Inf(1),
+		ComputedAt:         time.Now(),
+	}
+
+	total := scoreContrast(report.ForegroundContrast) * foregroundWeight
+	weight := float64(foregroundWeight)
+	for _, key := range models.ANSIColorKeys {
+		c, err := parseSlot(scheme, key)
+		if err != nil || !finite(c) {
+			report.InvalidKeys = append(report.InvalidKeys, key)
+			continue
+		}
+
+		onBg := contrastPair(key, c, "background", bg, key == bgSlot)
+		onFg := contrastPair(key, c, "foreground", fg, key == fgSlot)
+		report.Pairs = append(report.Pairs, onBg, onFg)
+
+		if onBg.Ignored {
+			continue
+		}
+		if !onBg.AA {
+			report.FailingAA = append(report.FailingAA, key)
+		}
+		report.MinContrast = math.Min(report.MinContrast, onBg.Ratio)
+		total += scoreContrast(onBg.Ratio)
+		weight++
+	}
+
+	report.MinContrast = math.Min(report.MinContrast, report.ForegroundContrast)
+	report.Score = round(100*total/weight, 1)
+
+	return report, nil
+}
+
+func contrastPair(key string, c color.RGB, against string, other color.RGB, ignored bool) models.ContrastPair {
+	ratio := color.ContrastRatio(c, other)
+	return models.ContrastPair{
+		Key:     key,
+		Color:   c.Hex(),
+		Against: against,
+		Ratio:   round(ratio, 2),
+		APCA:    round(color.APCAContrast(c, other), 1),
+		AALarge: ratio >= color.ContrastAALarge,
+		AA:      ratio >= color.ContrastAA,
+		AAA:     ratio >= color.ContrastAAA,
+		Ignored: ignored,
+	}
+}
+
+// scoreContrast maps a contrast ratio to [0, 1].
+func scoreContrast(ratio float64) float64 {
+	switch {
+	case ratio >= color.ContrastAAA:
+		return 1
+	case ratio >= color.ContrastAA:
+		return 0.8
+	case ratio >= color.ContrastAALarge:
+		return 0.5
+	default:
+		return 0.5 * (ratio - 1) / (color.ContrastAALarge - 1)
+	}
+}
+
+// finite reports whether every channel of c is a finite number.
+func finite(c color.RGB) bool {
+	for _, v := range []float64{c.R, c.G, c.B} {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
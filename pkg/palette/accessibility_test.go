@@ -0,0 +1,100 @@
+package palette
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+func TestAccessibility(t *testing.T) {
+	report, err := Accessibility(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Background != "#002b36" || report.Foreground != "#839496" {
+		t.Errorf("got background %s and foreground %s", report.Background, report.Foreground)
+	}
+	if !near(report.ForegroundContrast, 4.75, 0.01) {
+		t.Errorf("foreground contrast %v", report.ForegroundContrast)
+	}
+	// Every ANSI color against both the background and the foreground
+	if len(report.Pairs) != 32 {
+		t.Errorf("got %d pairs, want 32", len(report.Pairs))
+	}
+	// Solarized's accents sit around 4:1 on its background
+	want := []string{"black", "red", "blue", "magenta", "brightBlack", "brightRed", "brightGreen", "brightYellow", "brightMagenta"}
+	if !slices.Equal(report.FailingAA, want) {
+		t.Errorf("failing AA %v, want %v", report.FailingAA, want)
+	}
+	if report.Score <= 0 || report.Score >= 100 {
+		t.Errorf("score %v", report.Score)
+	}
+}
+
+func TestAccessibilityBlackOnWhite(t *testing.T) {
+	colors := map[string]string{"background": "#ffffff", "foreground": "#000000"}
+	for _, key := range chromaticKeys {
+		colors[key] = "#000000"
+	}
+	report, err := Accessibility(newScheme(colors))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if report.ForegroundContrast != 21 || report.MinContrast != 21 || report.Score != 100 {
+		t.Errorf("got contrast %v, min %v, score %v", report.ForegroundContrast, report.MinContrast, report.Score)
+	}
+	// Missing slots are reported rather than scored
+	want := []string{"black", "white", "brightBlack", "brightRed", "brightGreen", "brightYellow", "brightBlue", "brightMagenta", "brightCyan", "brightWhite"}
+	if !slices.Equal(report.InvalidKeys, want) {
+		t.Errorf("invalid keys %v, want %v", report.InvalidKeys, want)
+	}
+}
+
+// Without background and foreground keys, black and white stand in for
+// them and aren't scored against themselves.
+func TestAccessibilityBlackBackground(t *testing.T) {
+	report, err := Accessibility(newScheme(map[string]string{"black": "#000000", "white": "#ffffff", "red": "#ff0000"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, p := range report.Pairs {
+		ignored := (p.Key == "black" && p.Against == "background") || (p.Key == "white" && p.Against == "foreground")
+		if p.Ignored != ignored {
+			t.Errorf("%s on %s: ignored = %v", p.Key, p.Against, p.Ignored)
+		}
+	}
+	if slices.Contains(report.FailingAA, "black") {
+		t.Error("black fails AA against itself")
+	}
+}
+
+func TestAccessibilityNeedsBackground(t *testing.T) {
+	if _, err := Accessibility(newScheme(map[string]string{"red": "#ff0000"})); err == nil {
+		t.Error("want an error")
+	}
+}
+
+func TestFinite(t *testing.T) {
+	tests := []struct {
+		c    color.RGB
+		want bool
+	}{
+		{color.RGB{R: 0.5, G: 0, B: 1}, true},
+		// Out of gamut is still finite
+		{color.RGB{R: 2, G: -1, B: 0}, true},
+		{color.RGB{R: math.NaN()}, false},
+		{color.RGB{G: math.Inf(1)}, false},
+		{color.RGB{B: math.Inf(-1)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := finite(tt.c); got != tt.want {
+			t.Errorf("finite(%v) = %v, want %v", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package palette
+
+import (
+	"math"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// Thresholds used by Classify.
+const (
+	// Foreground contrast from which a scheme counts as high-contrast,
+	// well above AAA.
+	HighContrastRatio = 12.0
+	// Pastel schemes have light, soft ANSI colors.
+	pastelMinLightness = 0.75
+	pastelMaxChroma    = 0.12
+	// Monochrome schemes have almost no color, or all of it in one hue.
+	monochromeMaxChroma = 0.04
+	monochromeMinFocus  = 0.85
+	// Temperature beyond which a scheme counts as warm or cool.
+	temperatureThreshold = 0.15
+)
+
+// neutralKeys hold the tint of a scheme for its temperature.
+var neutralKeys = map[string]bool{
+	models.BackgroundKey: true, models.ForegroundKey: true,
+	"black": true, "white": true, "brightBlack": true, "brightWhite": true,
+}
+
+// warmHue is the OKLCH hue of orange, the warm end of the temperature axis.
+// The cool end is the opposite hue, a cyan-blue.
+const warmHue = 55.0
+
+// Classify computes the metrics of a scheme and the traits derived from
+// them:
+//   - dark or light, from the background's OKLCH lightness;
+//   - high-contrast, when the foreground reaches HighContrastRatio;
+//   - monochrome, when the ANSI colors have little chroma or share one hue;
+//   - pastel, when the ANSI colors are light and soft;
+//   - warm or cool, from the hues of the neutrals and of the other colors
+//     projected on the orange/blue axis, in [-1, 1].
+//
+// The returned category is Dark or Light.
+func Classify(scheme models.ColorScheme) (*models.SchemeMetrics, string, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return nil, "", err
+	}
+
+	metrics := &models.SchemeMetrics{
+		BackgroundLuminance: round(bg.RelativeLuminance(), 4),
+		ForegroundContrast:  round(color.ContrastRatio(fg, bg), 2),
+	}
+
+	// Chromatic ANSI colors, normal and bright
+	var chroma, lightness, focusX, focusY float64
+	n := 0
+	for _, key := range chromaticKeys {
+		for _, k := range []string{key, brightKey(key)} {
+			c, err := parseSlot(scheme, k)
+			if err != nil {
+				continue
+			}
+			o := c.OKLCH()
+			chroma += o.C
+			lightness += o.L
+			focusX += o.C * math.Cos(o.H*math.Pi/180)
+			focusY += o.C * math.Sin(o.H*math.Pi/180)
+			n++
+		}
+	}
+	// focus is 1 when every color has the same hue and near 0 when hues
+	// are spread around the wheel.
+	focus := 0.0
+	if n > 0 {
+		if chroma > 0 {
+			focus = math.Hypot(focusX, focusY) / chroma
+		}
+		chroma /= float64(n)
+		lightness /= float64(n)
+	}
+	metrics.AverageChroma = round(chroma, 4)
+
+	// Balanced ANSI hues cancel out, so the tint of the neutrals counts as
+	// much as the accents. Near-grays dilute the tint rather than letting
+	// the hue of a rounding error decide it.
+	var tint, tintWeight, accent, accentWeight float64
+	for key, value := range scheme.Colors {
+		c, err := color.Parse(value)
+		if err != nil {
+			continue
+		}
+		o := c.OKLCH()
+		w := o.C * math.Cos((o.H-warmHue)*math.Pi/180)
+		if neutralKeys[key] {
+			tint += w
+			tintWeight += math.Max(o.C, neutralChroma)
+		} else {
+			accent += w
+			accentWeight += o.C
+		}
+	}
+	if tintWeight > 0 {
+		metrics.Temperature += tint / tintWeight / 2
+	}
+	if accentWeight > 0 {
+		metrics.Temperature += accent / accentWeight / 2
+	}
+	metrics.Temperature = round(metrics.Temperature, 3)
+
+	category := CategoryLight
+	if bg.OKLCH().L < 0.5 {
+		category = CategoryDark
+		metrics.Traits = append(metrics.Traits, models.TraitDark)
+	} else {
+		metrics.Traits = append(metrics.Traits, models.TraitLight)
+	}
+
+	if metrics.ForegroundContrast >= HighContrastRatio {
+		metrics.Traits = append(metrics.Traits, models.TraitHighContrast)
+	}
+
+	monochrome := n == 0 || chroma < monochromeMaxChroma || focus >= monochromeMinFocus
+	if monochrome {
+		metrics.Traits = append(metrics.Traits, models.TraitMonochrome)
+	} else if lightness >= pastelMinLightness && chroma < pastelMaxChroma {
+		metrics.Traits = append(metrics.Traits, models.TraitPastel)
+	}
+
+	switch {
+	case metrics.Temperature >= temperatureThreshold:
+		metrics.Traits = append(metrics.Traits, models.TraitWarm)
+	case metrics.Temperature <= -temperatureThreshold:
+		metrics.Traits = append(metrics.Traits, models.TraitCool)
+	}
+
+	return metrics, category, nil
+}
@@ -0,0 +1,75 @@
+package palette
+
+import (
+	"sort"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// ConfusableDeltaE is the CIEDE2000 difference below which two terminal
+// colors are considered indistinguishable at a glance.
+const ConfusableDeltaE = 10.0
+
+// SimulateCVD returns a copy of scheme as seen under deficiency d, along
+// with the pairs of ANSI colors (and the background) that were
+// distinguishable originally but fall below ConfusableDeltaE once simulated.
+// Colors that fail to parse are copied unchanged.
+func SimulateCVD(scheme models.ColorScheme, d color.Deficiency, severity float64) *models.CVDSimulation {
+	simulated := scheme
+	simulated.Name = scheme.Name + " (" + string(d) + ")"
+	simulated.AccessibilityScore = nil
+	simulated.Colors = make(map[string]string, len(scheme.Colors))
+
+	original := make(map[string]color.Lab)
+	seen := make(map[string]color.Lab)
+	for key, value := range scheme.Colors {
+		c, err := color.Parse(value)
+		if err != nil {
+			simulated.Colors[key] = value
+			continue
+		}
+		sim := color.SimulateCVD(c, d, severity)
+		simulated.Colors[key] = sim.Hex()
+		original[key] = c.Lab()
+		seen[key] = sim.Lab()
+	}
+
+	keys := append([]string{}, models.ANSIColorKeys...)
+	if bg := scheme.BackgroundSlot(); bg != "black" {
+		keys = append(keys, bg)
+	}
+
+	pairs := []models.ConfusablePair{}
+	for i, a := range keys {
+		for _, b := range keys[i+1:] {
+			oa, okA := original[a]
+			ob, okB := original[b]
+			if !okA || !okB {
+				continue
+			}
+
+			before := color.DeltaE2000(oa, ob)
+			after := color.DeltaE2000(seen[a], seen[b])
+			if before >= ConfusableDeltaE && after < ConfusableDeltaE {
+				pairs = append(pairs, models.ConfusablePair{
+					A:               a,
+					B:               b,
+					OriginalDeltaE:  round(before, 2),
+					SimulatedDeltaE: round(after, 2),
+				})
+			}
+		}
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].SimulatedDeltaE < pairs[j].SimulatedDeltaE
+	})
+
+	return &models.CVDSimulation{
+		Deficiency:      string(d),
+		Severity:        severity,
+		Scheme:          simulated,
+		ConfusablePairs: pairs,
+	}
+}
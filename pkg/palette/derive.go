@@ -0,0 +1,186 @@
+package palette
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// Semantic roles filled in by Derive on top of the ANSI colors.
+const (
+	CursorKey    = "cursor"
+	SelectionKey = "selection"
+)
+
+// ansiHues are the OKLCH hues used for normal colors missing from a partial
+// palette.
+var ansiHues = map[string]float64{
+	"red":     25,
+	"yellow":  95,
+	"green":   145,
+	"cyan":    195,
+	"blue":    260,
+	"magenta": 330,
+}
+
+var chromaticKeys = []string{"red", "green", "yellow", "blue", "magenta", "cyan"}
+
+// Bright variants move this far in OKLCH lightness away from the background.
+const (
+	brightLightnessStep = 0.08
+	brightChromaFactor  = 1.05
+	// brightBlack is used for comments and must stay readable.
+	brightBlackMinContrast = color.ContrastAA
+)
+
+var ErrNoBackground = errors.New("palette needs a background or black color")
+
+// Derive completes a partial palette. Given at least a background (or
+// black), it fills every missing ANSI color, bright variant and semantic
+// role by adjusting lightness and chroma in OKLCH, and returns the full
+// palette with the sorted list of keys it added. Colors that were provided
+// are never changed.
+func Derive(colors map[string]string) (map[string]string, []string, error) {
+	out := make(map[string]string, len(colors)+len(models.ANSIColorKeys)+4)
+	parsed := make(map[string]color.OKLCH)
+	for key, value := range colors {
+		out[key] = value
+		if c, err := color.Parse(value); err == nil {
+			parsed[key] = c.OKLCH()
+		}
+	}
+
+	var added []string
+	set := func(key string, c color.OKLCH) {
+		if _, ok := out[key]; ok {
+			return
+		}
+		rgb := c.ClipToGamut()
+		out[key] = rgb.Hex()
+		parsed[key] = rgb.OKLCH()
+		added = append(added, key)
+	}
+
+	bg, ok := parsed[models.BackgroundKey]
+	if !ok {
+		if bg, ok = parsed["black"]; !ok {
+			return nil, nil, ErrNoBackground
+		}
+	}
+	dark := bg.L < 0.5
+
+	// Away from the background is lighter on dark schemes and darker on
+	// light ones
+	away := 1.0
+	if !dark {
+		away = -1
+	}
+
+	// black and white frame the palette: on a dark scheme black sits just
+	// above the background and white near the top, mirrored on light schemes
+	if dark {
+		set("black", color.OKLCH{L: math.Min(bg.L+0.04, 0.35), C: bg.C, H: bg.H})
+		set("white", color.OKLCH{L: 0.88, C: math.Min(bg.C, 0.02), H: bg.H})
+	} else {
+		set("black", color.OKLCH{L: 0.25, C: math.Min(bg.C, 0.02), H: bg.H})
+		set("white", color.OKLCH{L: math.Max(bg.L-0.08, 0.75), C: bg.C, H: bg.H})
+	}
+
+	// Missing hues copy the average lightness and chroma of those provided
+	l, c, n := 0.0, 0.0, 0
+	for _, key := range chromaticKeys {
+		if p, ok := parsed[key]; ok {
+			l += p.L
+			c += p.C
+			n++
+		}
+	}
+	if n == 0 {
+		l, c = 0.7, 0.14
+		if !dark {
+			l = 0.55
+		}
+	} else {
+		l, c = l/float64(n), c/float64(n)
+	}
+	for _, key := range chromaticKeys {
+		set(key, color.OKLCH{L: l, C: c, H: ansiHues[key]})
+	}
+
+	for _, key := range chromaticKeys {
+		base := parsed[key]
+		set(brightKey(key), color.OKLCH{
+			L: clampLightness(base.L + away*brightLightnessStep),
+			C: base.C * brightChromaFactor,
+			H: base.H,
+		})
+	}
+
+	fg, ok := parsed[models.ForegroundKey]
+	if !ok {
+		fg = parsed["white"]
+		if !dark {
+			fg = parsed["black"]
+		}
+	}
+
+	white := parsed["white"]
+	brightWhiteL := math.Min(white.L+brightLightnessStep, 1)
+	if !dark {
+		brightWhiteL = math.Max(white.L-brightLightnessStep, 0.6)
+	}
+	set("brightWhite", color.OKLCH{L: brightWhiteL, C: white.C, H: white.H})
+	set("brightBlack", dimmestReadable(bg, fg, brightBlackMinContrast))
+
+	set(models.BackgroundKey, bg)
+	set(models.ForegroundKey, fg)
+	set(CursorKey, fg)
+	set(SelectionKey, mix(bg, fg, 0.2))
+
+	sort.Strings(added)
+	return out, added, nil
+}
+
+func brightKey(key string) string {
+	return "bright" + string(key[0]-'a'+'A') + key[1:]
+}
+
+func clampLightness(l float64) float64 {
+	return math.Max(0.05, math.Min(0.97, l))
+}
+
+// dimmestReadable returns the color between bg and fg closest to bg that
+// still reaches minContrast against it. When even fg falls short, as on
+// mid-gray backgrounds, it looks towards white and black instead, starting
+// on fg's side.
+func dimmestReadable(bg, fg color.OKLCH, minContrast float64) color.OKLCH {
+	black, white := color.OKLCH{L: 0, H: fg.H}, color.OKLCH{L: 1, H: fg.H}
+	ends := []color.OKLCH{fg, white, black}
+	if fg.L < bg.L {
+		ends = []color.OKLCH{fg, black, white}
+	}
+
+	bgRGB := bg.ClipToGamut()
+	for _, end := range ends {
+		for i := 1; i <= 20; i++ {
+			c := mix(bg, end, float64(i)/20)
+			if color.ContrastRatio(c.ClipToGamut(), bgRGB) >= minContrast {
+				return c
+			}
+		}
+	}
+	return fg
+}
+
+// mix interpolates a towards b by t in OKLab.
+func mix(a, b color.OKLCH, t float64) color.OKLCH {
+	la, lb := a.OKLab(), b.OKLab()
+	return color.OKLab{
+		L: la.L + (lb.L-la.L)*t,
+		A: la.A + (lb.A-la.A)*t,
+		B: la.B + (lb.B-la.B)*t,
+	}.OKLCH()
+}
@@ -0,0 +1,181 @@
+package palette
+
+import (
+	"errors"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+var derivedKeys = []string{models.BackgroundKey, models.ForegroundKey, CursorKey, SelectionKey}
+
+func TestDeriveKeepsProvidedColors(t *testing.T) {
+	colors := map[string]string{
+		"background": "#1e1e2e",
+		"red":        "#F38BA8",
+		"blue":       "rgb(137, 180, 250)",
+		// Unparsable values are kept as they are too
+		"green":   "not a color",
+		"comment": "#6c7086",
+	}
+
+	got, added, err := Derive(colors)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, value := range colors {
+		if got[key] != value {
+			t.Errorf("%s = %q, want %q unchanged", key, got[key], value)
+		}
+	}
+	for _, key := range added {
+		if _, ok := colors[key]; ok {
+			t.Errorf("%s was provided but reported as added", key)
+		}
+	}
+	if !slices.IsSorted(added) {
+		t.Errorf("added is not sorted: %v", added)
+	}
+	if len(got) != len(colors)+len(added) {
+		t.Errorf("got %d colors, want %d provided plus %d added", len(got), len(colors), len(added))
+	}
+}
+
+func TestDeriveFillsEverySlot(t *testing.T) {
+	for _, bg := range []string{"#1e1e2e", "#282828", "#fdf6e3", "#ffffff", "#000000"} {
+		got, added, err := Derive(map[string]string{"background": bg})
+		if err != nil {
+			t.Fatalf("%s: %v", bg, err)
+		}
+		assertComplete(t, got, derivedKeys...)
+		if want := len(models.ANSIColorKeys) + len(derivedKeys) - 1; len(added) != want {
+			t.Errorf("%s: added %d keys, want %d", bg, len(added), want)
+		}
+	}
+}
+
+func TestDeriveComplete(t *testing.T) {
+	got, added, err := Derive(solarizedDark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the semantic roles are missing from a full scheme
+	if want := []string{CursorKey, SelectionKey}; !slices.Equal(added, want) {
+		t.Errorf("added %v, want %v", added, want)
+	}
+	if got[CursorKey] != solarizedDark["foreground"] {
+		t.Errorf("cursor = %s, want the foreground", got[CursorKey])
+	}
+	if !maps.Equal(solarizedDark, mapsWithout(got, CursorKey, SelectionKey)) {
+		t.Error("provided colors changed")
+	}
+}
+
+// brightBlack is used for comments, so it must reach AA against the
+// background whatever the background is.
+func TestDeriveBrightBlackReadable(t *testing.T) {
+	for _, bg := range []string{"#1e1e2e", "#002b36", "#282828", "#000000", "#fdf6e3", "#eff1f5", "#ffffff", "#777777", "#6b6b6b"} {
+		got, _, err := Derive(map[string]string{"background": bg})
+		if err != nil {
+			t.Fatalf("%s: %v", bg, err)
+		}
+		if ratio := contrast(t, got["brightBlack"], bg); ratio < color.ContrastAA {
+			t.Errorf("background %s: brightBlack %s has contrast %.2f, want at least %.1f", bg, got["brightBlack"], ratio, color.ContrastAA)
+		}
+	}
+}
+
+func TestDeriveDarkAndLight(t *testing.T) {
+	dark, _, err := Derive(map[string]string{"background": "#1e1e2e"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	light, _, err := Derive(map[string]string{"background": "#eff1f5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Bright colors move away from the background
+	for _, key := range chromaticKeys {
+		if l, b := lightness(t, dark[key]), lightness(t, dark[brightKey(key)]); b < l {
+			t.Errorf("dark: %s is darker than %s", brightKey(key), key)
+		}
+		if l, b := lightness(t, light[key]), lightness(t, light[brightKey(key)]); b > l {
+			t.Errorf("light: %s is lighter than %s", brightKey(key), key)
+		}
+	}
+
+	if lightness(t, dark[models.ForegroundKey]) < 0.5 {
+		t.Errorf("dark scheme got foreground %s", dark[models.ForegroundKey])
+	}
+	if lightness(t, light[models.ForegroundKey]) > 0.5 {
+		t.Errorf("light scheme got foreground %s", light[models.ForegroundKey])
+	}
+}
+
+func TestDeriveHuesFollowProvidedColors(t *testing.T) {
+	got, _, err := Derive(map[string]string{"background": "#1e1e2e", "red": "#f38ba8", "green": "#a6e3a1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	red, green, blue := oklch(t, got["red"]), oklch(t, got["green"]), oklch(t, got["blue"])
+	// A missing hue takes the average lightness and chroma of those given,
+	// less any chroma lost to the gamut
+	if !near(blue.L, (red.L+green.L)/2, 0.01) || blue.C > (red.C+green.C)/2+0.01 {
+		t.Errorf("blue %+v, want the average of %+v and %+v", blue, red, green)
+	}
+	if d := hueDelta(blue.H, ansiHues["blue"]); d < -5 || d > 5 {
+		t.Errorf("blue hue %.1f, want about %.0f", blue.H, ansiHues["blue"])
+	}
+}
+
+func TestDeriveBlackAsBackground(t *testing.T) {
+	got, added, err := Derive(map[string]string{"black": "#101010"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[models.BackgroundKey] != "#101010" || !slices.Contains(added, models.BackgroundKey) {
+		t.Errorf("background = %s, want black", got[models.BackgroundKey])
+	}
+}
+
+func TestDeriveNoBackground(t *testing.T) {
+	for _, colors := range []map[string]string{nil, {"red": "#ff0000"}, {"background": "nope"}} {
+		if _, _, err := Derive(colors); !errors.Is(err, ErrNoBackground) {
+			t.Errorf("Derive(%v) = %v, want ErrNoBackground", colors, err)
+		}
+	}
+}
+
+func TestBrightKey(t *testing.T) {
+	if got := brightKey("magenta"); got != "brightMagenta" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func oklch(t *testing.T, s string) color.OKLCH {
+	t.Helper()
+	c, err := color.Parse(s)
+	if err != nil {
+		t.Fatalf("parse %q: %v", s, err)
+	}
+	return c.OKLCH()
+}
+
+func lightness(t *testing.T, s string) float64 {
+	t.Helper()
+	return oklch(t, s).L
+}
+
+func mapsWithout(m map[string]string, keys ...string) map[string]string {
+	out := maps.Clone(m)
+	for _, key := range keys {
+		delete(out, key)
+	}
+	return out
+}
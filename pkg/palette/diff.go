@@ -0,0 +1,138 @@
+package palette
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// Resolutions accepted by Merge for conflicting keys.
+const (
+	ResolveLocal    = "local"
+	ResolveUpstream = "upstream"
+)
+
+// Diff compares two palettes slot by slot and returns the added, removed
+// and changed keys sorted by key. Colors are compared by their parsed
+// value, so "#FFF" and "#ffffff" are the same color.
+func Diff(from, to map[string]string) ([]models.ColorChange, int) {
+	var changes []models.ColorChange
+	unchanged := 0
+
+	for _, key := range unionKeys(from, to) {
+		a, inFrom := from[key]
+		b, inTo := to[key]
+
+		switch {
+		case !inFrom:
+			changes = append(changes, models.ColorChange{Key: key, Change: models.ChangeAdded, To: &b})
+		case !inTo:
+			changes = append(changes, models.ColorChange{Key: key, Change: models.ChangeRemoved, From: &a})
+		case sameColor(a, b):
+			unchanged++
+		default:
+			change := models.ColorChange{Key: key, Change: models.ChangeChanged, From: &a, To: &b}
+			ca, errA := color.Parse(a)
+			cb, errB := color.Parse(b)
+			if errA == nil && errB == nil {
+				d := round(color.DeltaE2000(ca.Lab(), cb.Lab()), 2)
+				change.DeltaE = &d
+			}
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, unchanged
+}
+
+// Merge applies the changes made between base and upstream to local. A key
+// changed on one side only takes that side's value; a key changed on both
+// sides to different values is a conflict, settled by resolutions (local or
+// upstream) and otherwise kept local. It returns the merged palette, the
+// keys taken from upstream and the conflicts.
+func Merge(base, local, upstream map[string]string, resolutions map[string]string) (map[string]string, []string, []models.MergeConflict) {
+	merged := make(map[string]string, len(local))
+	for key, value := range local {
+		merged[key] = value
+	}
+
+	var applied []string
+	var conflicts []models.MergeConflict
+	for _, key := range unionKeys(base, local, upstream) {
+		b, inBase := base[key]
+		l, inLocal := local[key]
+		u, inUpstream := upstream[key]
+
+		upstreamChanged := !sameSlot(b, inBase, u, inUpstream)
+		localChanged := !sameSlot(b, inBase, l, inLocal)
+		if !upstreamChanged || sameSlot(l, inLocal, u, inUpstream) {
+			continue
+		}
+
+		resolution := ResolveUpstream
+		if localChanged {
+			conflict := models.MergeConflict{Key: key, Resolution: ResolveLocal}
+			if inBase {
+				conflict.Base = &b
+			}
+			if inLocal {
+				conflict.Local = &l
+			}
+			if inUpstream {
+				conflict.Upstream = &u
+			}
+			if resolutions[key] == ResolveUpstream {
+				conflict.Resolution = ResolveUpstream
+			}
+			conflicts = append(conflicts, conflict)
+			resolution = conflict.Resolution
+		}
+
+		if resolution == ResolveUpstream {
+			if inUpstream {
+				merged[key] = u
+			} else {
+				delete(merged, key)
+			}
+			applied = append(applied, key)
+		}
+	}
+
+	return merged, applied, conflicts
+}
+
+// sameSlot reports whether two optional slot values are equal.
+func sameSlot(a string, okA bool, b string, okB bool) bool {
+	if okA != okB {
+		return false
+	}
+	return !okA || sameColor(a, b)
+}
+
+// sameColor compares two stored colors by value, falling back to a
+// case-insensitive string comparison for values that don't parse.
+func sameColor(a, b string) bool {
+	ca, errA := color.Parse(a)
+	cb, errB := color.Parse(b)
+	if errA != nil || errB != nil {
+		return strings.EqualFold(strings.TrimSpace(a), strings.TrimSpace(b))
+	}
+	return ca.Hex() == cb.Hex()
+}
+
+func unionKeys(palettes ...map[string]string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, p := range palettes {
+		for key := range p {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,156 @@
+package palette
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]string{
+		"background": "#FFF",
+		"red":        "#ff0000",
+		"green":      "#00ff00",
+		"comment":    "italic",
+		"old":        "#123456",
+	}
+	to := map[string]string{
+		"background": "#ffffff",
+		"red":        "#ee0000",
+		"green":      "rgb(0, 255, 0)",
+		"comment":    "ITALIC",
+		"new":        "#654321",
+	}
+
+	changes, unchanged := Diff(from, to)
+	// background, green and comment are equal by value
+	if unchanged != 3 {
+		t.Errorf("unchanged = %d, want 3", unchanged)
+	}
+
+	want := []struct {
+		key, change string
+	}{
+		{"new", models.ChangeAdded},
+		{"old", models.ChangeRemoved},
+		{"red", models.ChangeChanged},
+	}
+	if len(changes) != len(want) {
+		t.Fatalf("got %d changes, want %d: %+v", len(changes), len(want), changes)
+	}
+	for i, w := range want {
+		if changes[i].Key != w.key || changes[i].Change != w.change {
+			t.Errorf("change %d = %s %s, want %s %s", i, changes[i].Key, changes[i].Change, w.key, w.change)
+		}
+	}
+
+	if c := changes[0]; c.From != nil || c.To == nil || *c.To != "#654321" {
+		t.Errorf("added: %+v", c)
+	}
+	if c := changes[1]; c.To != nil || c.From == nil || *c.From != "#123456" {
+		t.Errorf("removed: %+v", c)
+	}
+	red := changes[2]
+	if *red.From != "#ff0000" || *red.To != "#ee0000" || red.DeltaE == nil || *red.DeltaE <= 0 || *red.DeltaE > 10 {
+		t.Errorf("changed: %+v", red)
+	}
+}
+
+func TestDiffUnparsableChange(t *testing.T) {
+	changes, _ := Diff(map[string]string{"comment": "italic"}, map[string]string{"comment": "bold"})
+	if len(changes) != 1 || changes[0].DeltaE != nil {
+		t.Errorf("got %+v, want one change without a delta", changes)
+	}
+}
+
+func TestDiffIdentical(t *testing.T) {
+	changes, unchanged := Diff(solarizedDark, solarizedDark)
+	if len(changes) != 0 || unchanged != len(solarizedDark) {
+		t.Errorf("got %d changes and %d unchanged", len(changes), unchanged)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := map[string]string{
+		"red":     "#ff0000",
+		"green":   "#00ff00",
+		"blue":    "#0000ff",
+		"yellow":  "#ffff00",
+		"cyan":    "#00ffff",
+		"magenta": "#ff00ff",
+	}
+	local := map[string]string{
+		"red":     "#ff0000",
+		"green":   "#11ff11", // changed locally only
+		"blue":    "#1111ff", // changed on both sides
+		"yellow":  "#eeee00", // changed on both sides, resolved upstream
+		"cyan":    "#00FFFF",
+		"magenta": "#ee00ee", // changed on both sides to the same color
+	}
+	upstream := map[string]string{
+		"red":     "#ee0000", // changed upstream only
+		"green":   "#00ff00",
+		"blue":    "#2222ff",
+		"yellow":  "#dddd00",
+		"magenta": "#EE00EE",
+		"orange":  "#ff8800", // added upstream
+		// cyan removed upstream
+	}
+
+	merged, applied, conflicts := Merge(base, local, upstream, map[string]string{"yellow": ResolveUpstream})
+
+	want := map[string]string{
+		"red":     "#ee0000",
+		"green":   "#11ff11",
+		"blue":    "#1111ff",
+		"yellow":  "#dddd00",
+		"magenta": "#ee00ee",
+		"orange":  "#ff8800",
+	}
+	if !maps.Equal(merged, want) {
+		t.Errorf("merged = %v, want %v", merged, want)
+	}
+	if want := []string{"cyan", "orange", "red", "yellow"}; !slices.Equal(applied, want) {
+		t.Errorf("applied = %v, want %v", applied, want)
+	}
+
+	if len(conflicts) != 2 {
+		t.Fatalf("got %d conflicts, want 2: %+v", len(conflicts), conflicts)
+	}
+	blue, yellow := conflicts[0], conflicts[1]
+	if blue.Key != "blue" || blue.Resolution != ResolveLocal || *blue.Base != "#0000ff" || *blue.Local != "#1111ff" || *blue.Upstream != "#2222ff" {
+		t.Errorf("blue conflict: %+v", blue)
+	}
+	if yellow.Key != "yellow" || yellow.Resolution != ResolveUpstream {
+		t.Errorf("yellow conflict: %+v", yellow)
+	}
+}
+
+func TestMergeRemovalConflict(t *testing.T) {
+	base := map[string]string{"red": "#ff0000"}
+	local := map[string]string{"red": "#ee0000"}
+	upstream := map[string]string{}
+
+	merged, _, conflicts := Merge(base, local, upstream, nil)
+	if merged["red"] != "#ee0000" {
+		t.Errorf("unresolved conflict lost the local value: %v", merged)
+	}
+	if len(conflicts) != 1 || conflicts[0].Upstream != nil {
+		t.Fatalf("got %+v, want a conflict with no upstream value", conflicts)
+	}
+
+	merged, applied, _ := Merge(base, local, upstream, map[string]string{"red": ResolveUpstream})
+	if _, ok := merged["red"]; ok || !slices.Equal(applied, []string{"red"}) {
+		t.Errorf("resolved upstream: merged %v, applied %v", merged, applied)
+	}
+}
+
+func TestMergeLeavesLocalAlone(t *testing.T) {
+	local := map[string]string{"red": "#ff0000"}
+	Merge(map[string]string{}, local, map[string]string{"red": "#00ff00"}, nil)
+	if local["red"] != "#ff0000" {
+		t.Error("Merge modified local")
+	}
+}
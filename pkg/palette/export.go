@@ -0,0 +1,171 @@
+package palette
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+// ExportFormat is a terminal configuration format a scheme can be written
+// in.
+type ExportFormat struct {
+	Name        string
+	Extension   string
+	ContentType string
+	render      func(name string, p exportPalette) ([]byte, error)
+}
+
+var exportFormats = map[string]ExportFormat{
+	"alacritty":        {Name: "alacritty", Extension: ".toml", ContentType: "application/toml", render: renderAlacritty},
+	"kitty":            {Name: "kitty", Extension: ".conf", ContentType: "text/plain; charset=utf-8", render: renderKitty},
+	"xresources":       {Name: "xresources", Extension: ".Xresources", ContentType: "text/plain; charset=utf-8", render: renderXresources},
+	"windows-terminal": {Name: "windows-terminal", Extension: ".json", ContentType: "application/json", render: renderWindowsTerminal},
+}
+
+// LookupExportFormat returns the format called name.
+func LookupExportFormat(name string) (ExportFormat, bool) {
+	f, ok := exportFormats[name]
+	return f, ok
+}
+
+// ExportFormatNames lists the supported formats sorted by name.
+func ExportFormatNames() []string {
+	names := make([]string, 0, len(exportFormats))
+	for name := range exportFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FileName names the exported file after the scheme.
+func (f ExportFormat) FileName(scheme models.ColorScheme) string {
+	return Slug(scheme.Name, scheme.ID) + f.Extension
+}
+
+// Slug turns name into a lowercase, hyphenated file name, or returns
+// fallback when name has no ASCII letters or digits.
+func Slug(name, fallback string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(name) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		} else {
+			hyphen = true
+		}
+	}
+	if b.Len() == 0 {
+		return fallback
+	}
+	return b.String()
+}
+
+// Export writes the scheme in the format. Missing ANSI colors take the
+// xterm defaults, a missing cursor the foreground and a missing selection a
+// mix of background and foreground.
+func (f ExportFormat) Export(scheme models.ColorScheme) ([]byte, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	p := exportPalette{Background: bg.Hex(), Foreground: fg.Hex()}
+	for i, key := range models.ANSIColorKeys {
+		p.ANSI[i] = slotOrDefault(scheme, key, i).Hex()
+	}
+
+	p.Cursor = p.Foreground
+	if c, err := parseSlot(scheme, CursorKey); err == nil {
+		p.Cursor = c.Hex()
+	}
+	p.Selection = lerpLab(bg.Lab(), fg.Lab(), 0.2).RGB().Clamp().Hex()
+	if c, err := parseSlot(scheme, SelectionKey); err == nil {
+		p.Selection = c.Hex()
+	}
+
+	// Names end up in comments, so they must stay on one line
+	return f.render(strings.Join(strings.Fields(scheme.Name), " "), p)
+}
+
+// exportPalette holds the resolved colors every format writes.
+type exportPalette struct {
+	Background, Foreground, Cursor, Selection string
+	ANSI                                      [16]string
+}
+
+// ansiNames are the ANSI color names without the bright prefix, as
+// Alacritty's normal and bright tables use them.
+var ansiNames = [8]string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+func renderAlacritty(name string, p exportPalette) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", name)
+	fmt.Fprintf(&b, "[colors.primary]\nbackground = %q\nforeground = %q\n\n", p.Background, p.Foreground)
+	fmt.Fprintf(&b, "[colors.cursor]\ncursor = %q\ntext = %q\n\n", p.Cursor, p.Background)
+	fmt.Fprintf(&b, "[colors.selection]\nbackground = %q\ntext = %q\n", p.Selection, p.Foreground)
+	for i, table := range []string{"normal", "bright"} {
+		fmt.Fprintf(&b, "\n[colors.%s]\n", table)
+		for j, n := range ansiNames {
+			fmt.Fprintf(&b, "%s = %q\n", n, p.ANSI[i*8+j])
+		}
+	}
+	return b.Bytes(), nil
+}
+
+func renderKitty(name string, p exportPalette) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n\n", name)
+	fmt.Fprintf(&b, "background %s\nforeground %s\n", p.Background, p.Foreground)
+	fmt.Fprintf(&b, "cursor %s\ncursor_text_color %s\n", p.Cursor, p.Background)
+	fmt.Fprintf(&b, "selection_background %s\nselection_foreground %s\n\n", p.Selection, p.Foreground)
+	for i, c := range p.ANSI {
+		fmt.Fprintf(&b, "color%d %s\n", i, c)
+	}
+	return b.Bytes(), nil
+}
+
+func renderXresources(name string, p exportPalette) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "! %s\n\n", name)
+	fmt.Fprintf(&b, "*.background: %s\n*.foreground: %s\n*.cursorColor: %s\n\n", p.Background, p.Foreground, p.Cursor)
+	for i, c := range p.ANSI {
+		fmt.Fprintf(&b, "*.color%d: %s\n", i, c)
+	}
+	return b.Bytes(), nil
+}
+
+// renderWindowsTerminal writes an entry for the "schemes" list of Windows
+// Terminal's settings.json, which calls magenta purple.
+func renderWindowsTerminal(name string, p exportPalette) ([]byte, error) {
+	keys := [16]string{
+		"black", "red", "green", "yellow", "blue", "purple", "cyan", "white",
+		"brightBlack", "brightRed", "brightGreen", "brightYellow",
+		"brightBlue", "brightPurple", "brightCyan", "brightWhite",
+	}
+
+	scheme := map[string]string{
+		"name":                name,
+		"background":          p.Background,
+		"foreground":          p.Foreground,
+		"cursorColor":         p.Cursor,
+		"selectionBackground": p.Selection,
+	}
+	for i, key := range keys {
+		scheme[key] = p.ANSI[i]
+	}
+
+	data, err := json.MarshalIndent(scheme, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
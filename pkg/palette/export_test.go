@@ -0,0 +1,129 @@
+package palette
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func TestExportGolden(t *testing.T) {
+	scheme := newScheme(solarizedDark)
+	scheme.Colors[CursorKey] = "#93a1a1"
+
+	for _, name := range ExportFormatNames() {
+		t.Run(name, func(t *testing.T) {
+			f, _ := LookupExportFormat(name)
+			got, err := f.Export(scheme)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			golden := filepath.Join("testdata", "solarized-dark."+name+".golden")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("output differs from %s, run with -update if intended:\n%s", golden, got)
+			}
+		})
+	}
+}
+
+func TestExportFormats(t *testing.T) {
+	want := []string{"alacritty", "kitty", "windows-terminal", "xresources"}
+	if got := ExportFormatNames(); !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := LookupExportFormat("iterm2"); ok {
+		t.Error("iterm2 is not supported")
+	}
+}
+
+func TestExportDefaults(t *testing.T) {
+	scheme := newScheme(map[string]string{"background": "#000000", "foreground": "#ffffff", "red": "#dc322f"})
+	f, _ := LookupExportFormat("windows-terminal")
+	data, err := f.Export(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["red"] != "#dc322f" {
+		t.Errorf("red = %s", got["red"])
+	}
+	// Missing colors fall back to the xterm defaults, the foreground and a
+	// background/foreground mix
+	if want := color.Xterm256[4].Hex(); got["blue"] != want {
+		t.Errorf("blue = %s, want the default %s", got["blue"], want)
+	}
+	if got["cursorColor"] != "#ffffff" {
+		t.Errorf("cursor = %s, want the foreground", got["cursorColor"])
+	}
+	if l := lightness(t, got["selectionBackground"]); l <= 0 || l >= 0.5 {
+		t.Errorf("selection = %s, want a dark gray", got["selectionBackground"])
+	}
+}
+
+func TestExportNameOnOneLine(t *testing.T) {
+	scheme := newScheme(solarizedDark)
+	scheme.Name = "Solarized\nDark\t \r\n[colors]"
+	f, _ := LookupExportFormat("alacritty")
+	data, err := f.Export(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first, _, _ := strings.Cut(string(data), "\n"); first != "# Solarized Dark [colors]" {
+		t.Errorf("first line %q", first)
+	}
+}
+
+func TestExportNeedsBackground(t *testing.T) {
+	f, _ := LookupExportFormat("kitty")
+	if _, err := f.Export(models.ColorScheme{Colors: map[string]string{"red": "#ff0000"}}); err == nil {
+		t.Error("want an error")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := []struct {
+		name, want string
+	}{
+		{"Solarized Dark", "solarized-dark"},
+		{"  Gruvbox -- Material!  ", "gruvbox-material"},
+		{"Catppuccin (Mocha) v2", "catppuccin-mocha-v2"},
+		{"日本", "fallback"},
+		{"", "fallback"},
+	}
+
+	for _, tt := range tests {
+		if got := Slug(tt.name, "fallback"); got != tt.want {
+			t.Errorf("Slug(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFileName(t *testing.T) {
+	f, _ := LookupExportFormat("xresources")
+	if got := f.FileName(models.ColorScheme{ID: "abc123", Name: "???"}); got != "abc123.Xresources" {
+		t.Errorf("got %s", got)
+	}
+}
@@ -0,0 +1,316 @@
+package palette
+
+import (
+	"errors"
+	"image"
+	"math"
+	"math/rand/v2"
+	"sort"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// DefaultClusters is the number of colors an image is quantized to when
+// ExtractOptions.Clusters is zero.
+const DefaultClusters = 16
+
+// Quantization works on a grid of at most this many pixels, large images are
+// sampled with a stride.
+const maxSamples = 1 << 14
+
+const (
+	kmeansIterations = 24
+	kmeansTolerance  = 1e-4
+)
+
+// A cluster must hold this share of the image to be picked as background or
+// foreground, so a few stray pixels can't set the tone of the scheme.
+const minToneWeight = 0.05
+
+// A cluster is only used for an ANSI color when its hue is within this many
+// degrees of the slot's hue and no other slot is closer, otherwise the slot
+// keeps its canonical hue pulled towards the image's colors.
+const maxHueDistance = 40.0
+
+var ErrEmptyImage = errors.New("image has no opaque pixels")
+
+type ExtractOptions struct {
+	Clusters int
+	// Dark forces a dark or light scheme, nil follows the image's average
+	// lightness.
+	Dark *bool
+}
+
+// Cluster is one color of a quantized image with the share of sampled
+// pixels it represents.
+type Cluster struct {
+	Color  color.RGB
+	Weight float64
+}
+
+// Quantize reduces img to at most k colors by k-means in OKLab. Transparent
+// pixels are ignored. Initialization uses k-means++ with a fixed seed, so
+// the same image always gives the same clusters. The result is sorted by
+// weight, largest first.
+func Quantize(img image.Image, k int) []Cluster {
+	samples := samplePixels(img)
+	if len(samples) == 0 || k <= 0 {
+		return nil
+	}
+	if k > len(samples) {
+		k = len(samples)
+	}
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	centers := initCenters(samples, k, rng)
+	assign := make([]int, len(samples))
+
+	for range kmeansIterations {
+		for i, s := range samples {
+			assign[i] = nearestCenter(s, centers)
+		}
+
+		sums := make([]color.OKLab, k)
+		counts := make([]int, k)
+		for i, s := range samples {
+			c := assign[i]
+			sums[c].L += s.L
+			sums[c].A += s.A
+			sums[c].B += s.B
+			counts[c]++
+		}
+
+		moved := 0.0
+		for c := range centers {
+			if counts[c] == 0 {
+				continue
+			}
+			n := float64(counts[c])
+			next := color.OKLab{L: sums[c].L / n, A: sums[c].A / n, B: sums[c].B / n}
+			moved = math.Max(moved, labDistance(next, centers[c]))
+			centers[c] = next
+		}
+		if moved < kmeansTolerance {
+			break
+		}
+	}
+
+	counts := make([]int, k)
+	for _, s := range samples {
+		counts[nearestCenter(s, centers)]++
+	}
+
+	clusters := make([]Cluster, 0, k)
+	for c, center := range centers {
+		if counts[c] == 0 {
+			continue
+		}
+		clusters = append(clusters, Cluster{
+			Color:  center.RGB().Clamp(),
+			Weight: float64(counts[c]) / float64(len(samples)),
+		})
+	}
+	sort.SliceStable(clusters, func(i, j int) bool { return clusters[i].Weight > clusters[j].Weight })
+	return clusters
+}
+
+// Extract builds a palette matching img. The image is quantized, the
+// dominant dark and light clusters become background and foreground, and
+// each ANSI color takes the hue of the closest chromatic cluster. Colors are
+// lifted to AA contrast against the background (AAA for the foreground) and
+// the remaining slots are filled in by Derive. The clusters are returned
+// alongside the palette.
+func Extract(img image.Image, opts ExtractOptions) (map[string]string, []Cluster, error) {
+	k := opts.Clusters
+	if k <= 0 {
+		k = DefaultClusters
+	}
+
+	clusters := Quantize(img, k)
+	if len(clusters) == 0 {
+		return nil, nil, ErrEmptyImage
+	}
+
+	lightness := 0.0
+	for _, c := range clusters {
+		lightness += c.Color.OKLCH().L * c.Weight
+	}
+	dark := lightness < 0.5
+	if opts.Dark != nil {
+		dark = *opts.Dark
+	}
+
+	bg, fg := toneClusters(clusters, dark)
+	if dark {
+		bg.L = math.Min(bg.L, 0.22)
+		fg.L = math.Max(fg.L, 0.9)
+	} else {
+		bg.L = math.Max(bg.L, 0.96)
+		fg.L = math.Min(fg.L, 0.3)
+	}
+	bg.C = math.Min(bg.C, 0.04)
+	fg.C = math.Min(fg.C, 0.03)
+	bgRGB := bg.ClipToGamut()
+
+	colors := map[string]string{
+		models.BackgroundKey: bgRGB.Hex(),
+		models.ForegroundKey: ensureContrast(fg.ClipToGamut(), bgRGB, color.ContrastAAA).Hex(),
+	}
+
+	var chromatic []color.OKLCH
+	var hues []float64
+	for _, c := range clusters {
+		if o := c.Color.OKLCH(); o.C >= neutralChroma {
+			chromatic = append(chromatic, o)
+			hues = append(hues, o.H)
+		}
+	}
+
+	target := 0.72
+	if !dark {
+		target = 0.55
+	}
+	for _, key := range chromaticKeys {
+		c := color.OKLCH{L: target, C: 0.1, H: ansiHues[key]}
+		if len(hues) > 0 {
+			c.H = pullHue(c.H, hues)
+		}
+
+		// Each cluster only counts for the slot whose hue it is closest to,
+		// so one dominant color doesn't fill both red and magenta.
+		best := -1
+		for i, o := range chromatic {
+			d := math.Abs(hueDelta(ansiHues[key], o.H))
+			if d > maxHueDistance || closestSlot(o.H) != key {
+				continue
+			}
+			if best < 0 || d < math.Abs(hueDelta(ansiHues[key], chromatic[best].H)) {
+				best = i
+			}
+		}
+		if best >= 0 {
+			match := chromatic[best]
+			c = color.OKLCH{
+				L: (match.L + target) / 2,
+				C: math.Max(match.C, 0.06),
+				H: match.H,
+			}
+		}
+
+		colors[key] = ensureContrast(c.ClipToGamut(), bgRGB, color.ContrastAA).Hex()
+	}
+
+	full, _, err := Derive(colors)
+	if err != nil {
+		return nil, nil, err
+	}
+	return full, clusters, nil
+}
+
+// closestSlot returns the chromatic ANSI key whose hue is nearest h.
+func closestSlot(h float64) string {
+	best := chromaticKeys[0]
+	for _, key := range chromaticKeys[1:] {
+		if math.Abs(hueDelta(ansiHues[key], h)) < math.Abs(hueDelta(ansiHues[best], h)) {
+			best = key
+		}
+	}
+	return best
+}
+
+// toneClusters picks the background and foreground candidates: for a dark
+// scheme the darkest and lightest clusters holding at least minToneWeight
+// of the image, the other way round for a light one.
+func toneClusters(clusters []Cluster, dark bool) (color.OKLCH, color.OKLCH) {
+	candidates := make([]color.OKLCH, 0, len(clusters))
+	for _, c := range clusters {
+		if c.Weight >= minToneWeight {
+			candidates = append(candidates, c.Color.OKLCH())
+		}
+	}
+	if len(candidates) == 0 {
+		for _, c := range clusters {
+			candidates = append(candidates, c.Color.OKLCH())
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].L < candidates[j].L })
+	darkest, lightest := candidates[0], candidates[len(candidates)-1]
+	if dark {
+		return darkest, lightest
+	}
+	return lightest, darkest
+}
+
+// samplePixels returns the OKLab values of img's opaque pixels on a grid of
+// at most maxSamples points.
+func samplePixels(img image.Image) []color.OKLab {
+	b := img.Bounds()
+	if b.Empty() {
+		return nil
+	}
+
+	stride := max(1, int(math.Ceil(math.Sqrt(float64(b.Dx()*b.Dy())/maxSamples))))
+	samples := make([]color.OKLab, 0, min(maxSamples, b.Dx()*b.Dy()))
+	for y := b.Min.Y; y < b.Max.Y; y += stride {
+		for x := b.Min.X; x < b.Max.X; x += stride {
+			r, g, bl, a := img.At(x, y).RGBA()
+			if a < 0x8000 {
+				continue
+			}
+			// RGBA is alpha-premultiplied
+			r, g, bl = r*0xffff/a, g*0xffff/a, bl*0xffff/a
+			samples = append(samples, color.RGB{
+				R: float64(r) / 0xffff,
+				G: float64(g) / 0xffff,
+				B: float64(bl) / 0xffff,
+			}.OKLab())
+		}
+	}
+	return samples
+}
+
+// initCenters picks k starting centers with k-means++: each next center is
+// drawn with probability proportional to its squared distance from the
+// closest center chosen so far.
+func initCenters(samples []color.OKLab, k int, rng *rand.Rand) []color.OKLab {
+	centers := make([]color.OKLab, 0, k)
+	centers = append(centers, samples[rng.IntN(len(samples))])
+
+	dist := make([]float64, len(samples))
+	for len(centers) < k {
+		total := 0.0
+		for i, s := range samples {
+			d := labDistance(s, centers[nearestCenter(s, centers)])
+			dist[i] = d * d
+			total += dist[i]
+		}
+		// Fewer distinct colors than clusters
+		if total == 0 {
+			break
+		}
+
+		pick := rng.Float64() * total
+		i := 0
+		for ; i < len(samples)-1 && pick >= dist[i]; i++ {
+			pick -= dist[i]
+		}
+		centers = append(centers, samples[i])
+	}
+	return centers
+}
+
+func nearestCenter(s color.OKLab, centers []color.OKLab) int {
+	best, bestDist := 0, math.Inf(1)
+	for i, c := range centers {
+		if d := labDistance(s, c); d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return best
+}
+
+func labDistance(a, b color.OKLab) float64 {
+	return math.Sqrt((a.L-b.L)*(a.L-b.L) + (a.A-b.A)*(a.A-b.A) + (a.B-b.B)*(a.B-b.B))
+}
@@ -0,0 +1,154 @@
+package palette
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"reflect"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	colorpkg "github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// splitImage fills the left share of a w x h image with a and the rest
+// with b.
+func splitImage(w, h int, share float64, a, b color.Color) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	split := int(float64(w) * share)
+	for y := range h {
+		for x := range w {
+			if x < split {
+				img.Set(x, y, a)
+			} else {
+				img.Set(x, y, b)
+			}
+		}
+	}
+	return img
+}
+
+var (
+	navy  = color.NRGBA{0x10, 0x18, 0x30, 0xff}
+	amber = color.NRGBA{0xff, 0xb0, 0x20, 0xff}
+)
+
+func TestQuantizeTwoColors(t *testing.T) {
+	clusters := Quantize(splitImage(40, 10, 0.75, navy, amber), 2)
+	if len(clusters) != 2 {
+		t.Fatalf("got %d clusters, want 2", len(clusters))
+	}
+
+	// Sorted by weight, each cluster is exactly one of the two colors
+	want := []struct {
+		hex    string
+		weight float64
+	}{{"#101830", 0.75}, {"#ffb020", 0.25}}
+	for i, w := range want {
+		if got := clusters[i].Color.Hex(); got != w.hex {
+			t.Errorf("cluster %d is %s, want %s", i, got, w.hex)
+		}
+		if !near(clusters[i].Weight, w.weight, 1e-9) {
+			t.Errorf("cluster %d weighs %v, want %v", i, clusters[i].Weight, w.weight)
+		}
+	}
+}
+
+func TestQuantizeMoreClustersThanColors(t *testing.T) {
+	clusters := Quantize(splitImage(20, 20, 0.5, navy, amber), 8)
+	if len(clusters) != 2 {
+		t.Errorf("got %d clusters, want 2", len(clusters))
+	}
+
+	total := 0.0
+	for _, c := range clusters {
+		total += c.Weight
+	}
+	if !near(total, 1, 1e-9) {
+		t.Errorf("weights sum to %v", total)
+	}
+}
+
+func TestQuantizeDeterministic(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 64, 64))
+	for y := range 64 {
+		for x := range 64 {
+			img.Set(x, y, color.NRGBA{uint8(x * 4), uint8(y * 4), uint8((x + y) * 2), 0xff})
+		}
+	}
+
+	a, b := Quantize(img, 8), Quantize(img, 8)
+	if len(a) != 8 {
+		t.Fatalf("got %d clusters, want 8", len(a))
+	}
+	if !reflect.DeepEqual(a, b) {
+		t.Error("the same image gave different clusters")
+	}
+}
+
+func TestQuantizeIgnoresTransparentPixels(t *testing.T) {
+	img := splitImage(10, 10, 0.5, navy, color.NRGBA{0xff, 0, 0, 0})
+	clusters := Quantize(img, 4)
+	if len(clusters) != 1 || clusters[0].Color.Hex() != "#101830" || clusters[0].Weight != 1 {
+		t.Errorf("got %+v, want only navy", clusters)
+	}
+
+	if got := Quantize(image.NewNRGBA(image.Rect(0, 0, 4, 4)), 4); got != nil {
+		t.Errorf("transparent image gave %+v", got)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	colors, clusters, err := Extract(splitImage(40, 10, 0.75, navy, amber), ExtractOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != 2 {
+		t.Errorf("got %d clusters, want 2", len(clusters))
+	}
+	assertComplete(t, colors, derivedKeys...)
+
+	bg := colors[models.BackgroundKey]
+	if lightness(t, bg) >= 0.5 {
+		t.Errorf("a mostly navy image gave background %s", bg)
+	}
+	if ratio := contrast(t, colors[models.ForegroundKey], bg); ratio < colorpkg.ContrastAAA {
+		t.Errorf("foreground contrast %.2f, want AAA", ratio)
+	}
+	for _, key := range chromaticKeys {
+		if ratio := contrast(t, colors[key], bg); ratio < colorpkg.ContrastAA {
+			t.Errorf("%s contrast %.2f, want AA", key, ratio)
+		}
+	}
+
+	// Amber is closest to yellow and takes that slot's hue
+	amberHue := colorpkg.FromRGB8(amber.R, amber.G, amber.B).OKLCH().H
+	if got := oklch(t, colors["yellow"]).H; !near(got, amberHue, 3) {
+		t.Errorf("yellow hue %.1f, want amber's %.1f", got, amberHue)
+	}
+}
+
+func TestExtractForcedTone(t *testing.T) {
+	light := false
+	colors, _, err := Extract(splitImage(40, 10, 0.75, navy, amber), ExtractOptions{Dark: &light})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bg := colors[models.BackgroundKey]; lightness(t, bg) < 0.9 {
+		t.Errorf("forced light scheme got background %s", bg)
+	}
+}
+
+func TestExtractEmptyImage(t *testing.T) {
+	if _, _, err := Extract(image.NewNRGBA(image.Rect(0, 0, 4, 4)), ExtractOptions{}); !errors.Is(err, ErrEmptyImage) {
+		t.Errorf("got %v, want ErrEmptyImage", err)
+	}
+}
+
+func TestClosestSlot(t *testing.T) {
+	for key, h := range ansiHues {
+		if got := closestSlot(h + 5); got != key {
+			t.Errorf("closestSlot(%v) = %s, want %s", h+5, got, key)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package palette
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+type Harmony string
+
+const (
+	Analogous        Harmony = "analogous"
+	Triadic          Harmony = "triadic"
+	Complementary    Harmony = "complementary"
+	TintedMonochrome Harmony = "tinted-monochrome"
+)
+
+func (h Harmony) Valid() bool {
+	switch h {
+	case Analogous, Triadic, Complementary, TintedMonochrome:
+		return true
+	}
+	return false
+}
+
+// harmonyOffsets are the hue offsets from the first seed that make up each
+// harmony.
+var harmonyOffsets = map[Harmony][]float64{
+	Analogous:     {-60, -30, 0, 30, 60},
+	Triadic:       {0, 120, 240},
+	Complementary: {0, 180},
+}
+
+// How far each ANSI hue is pulled towards its nearest harmony hue. Less
+// than one keeps red recognizably red.
+const harmonyPull = 0.5
+
+// Random variation applied per color, scaled by the generator's seed.
+const (
+	hueJitter       = 8.0
+	lightnessJitter = 0.03
+)
+
+type GenerateOptions struct {
+	Seeds   []color.RGB
+	Harmony Harmony
+	Dark    bool
+	// Seed drives the random variation, the same options always produce
+	// the same palette.
+	Seed int64
+}
+
+var ErrNoSeeds = errors.New("at least one seed color is required")
+
+// Generate builds a full palette from seed colors. The first seed sets the
+// background tint and the harmony's base hue; with more than one seed the
+// seeds' hues are used as the harmony directly. ANSI hues are pulled
+// towards the harmony, lifted to AA contrast against the background, and
+// the remaining slots are filled in by Derive.
+func Generate(opts GenerateOptions) (map[string]string, error) {
+	if len(opts.Seeds) == 0 {
+		return nil, ErrNoSeeds
+	}
+
+	rng := rand.New(rand.NewPCG(uint64(opts.Seed), uint64(opts.Seed)^0x9e3779b97f4a7c15))
+	base := opts.Seeds[0].OKLCH()
+
+	hues := harmonyHues(base.H, opts)
+	chroma := 0.0
+	for _, s := range opts.Seeds {
+		chroma += s.OKLCH().C
+	}
+	chroma = math.Max(0.08, math.Min(0.18, chroma/float64(len(opts.Seeds))))
+
+	bg := color.OKLCH{L: 0.2, C: math.Min(base.C*0.15, 0.02), H: base.H}
+	lightness := 0.72
+	if !opts.Dark {
+		bg.L = 0.97
+		lightness = 0.55
+	}
+	bgRGB := bg.ClipToGamut()
+
+	colors := map[string]string{models.BackgroundKey: bgRGB.Hex()}
+	for i, key := range chromaticKeys {
+		c := color.OKLCH{
+			L: lightness + (rng.Float64()*2-1)*lightnessJitter,
+			C: chroma,
+			H: pullHue(ansiHues[key], hues) + (rng.Float64()*2-1)*hueJitter,
+		}
+
+		// Monochrome palettes tell colors apart by lightness instead of hue
+		if opts.Harmony == TintedMonochrome {
+			step := float64(i) - float64(len(chromaticKeys)-1)/2
+			c.L = lightness + step*0.04
+			c.C = chroma * 0.35
+			c.H = base.H
+		}
+
+		colors[key] = ensureContrast(c.ClipToGamut(), bgRGB, color.ContrastAA).Hex()
+	}
+
+	full, _, err := Derive(colors)
+	if err != nil {
+		return nil, err
+	}
+	return full, nil
+}
+
+// harmonyHues returns the target hues for the palette.
+func harmonyHues(base float64, opts GenerateOptions) []float64 {
+	if len(opts.Seeds) > 1 {
+		hues := make([]float64, len(opts.Seeds))
+		for i, s := range opts.Seeds {
+			hues[i] = s.OKLCH().H
+		}
+		return hues
+	}
+
+	offsets, ok := harmonyOffsets[opts.Harmony]
+	if !ok {
+		return []float64{base}
+	}
+	hues := make([]float64, len(offsets))
+	for i, o := range offsets {
+		hues[i] = math.Mod(base+o+360, 360)
+	}
+	return hues
+}
+
+// pullHue moves h part of the way towards the closest of targets.
+func pullHue(h float64, targets []float64) float64 {
+	closest := math.Inf(1)
+	for _, t := range targets {
+		if d := hueDelta(h, t); math.Abs(d) < math.Abs(closest) {
+			closest = d
+		}
+	}
+	return math.Mod(h+closest*harmonyPull+360, 360)
+}
+
+// hueDelta is the signed shortest rotation from a to b, in (-180, 180].
+func hueDelta(a, b float64) float64 {
+	d := math.Mod(b-a, 360)
+	if d > 180 {
+		d -= 360
+	} else if d <= -180 {
+		d += 360
+	}
+	return d
+}
@@ -0,0 +1,159 @@
+package palette
+
+import (
+	"errors"
+	"maps"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+var harmonies = []Harmony{Analogous, Triadic, Complementary, TintedMonochrome}
+
+func TestGenerateDeterministic(t *testing.T) {
+	for _, h := range harmonies {
+		opts := GenerateOptions{Seeds: []color.RGB{color.MustParse("#268bd2")}, Harmony: h, Dark: true, Seed: 42}
+		a, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", h, err)
+		}
+		b, err := Generate(opts)
+		if err != nil {
+			t.Fatalf("%s: %v", h, err)
+		}
+		if !maps.Equal(a, b) {
+			t.Errorf("%s: the same seed gave different palettes", h)
+		}
+	}
+}
+
+func TestGenerateSeedVaries(t *testing.T) {
+	opts := GenerateOptions{Seeds: []color.RGB{color.MustParse("#268bd2")}, Harmony: Triadic, Dark: true, Seed: 1}
+	a, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts.Seed = 2
+	b, err := Generate(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if maps.Equal(a, b) {
+		t.Error("different seeds gave the same palette")
+	}
+}
+
+func TestGenerateReadable(t *testing.T) {
+	seeds := []string{"#268bd2", "#ff5555", "#50fa7b", "#808080"}
+	for _, dark := range []bool{true, false} {
+		for _, h := range harmonies {
+			for _, s := range seeds {
+				colors, err := Generate(GenerateOptions{Seeds: []color.RGB{color.MustParse(s)}, Harmony: h, Dark: dark, Seed: 7})
+				if err != nil {
+					t.Fatalf("%s %s: %v", h, s, err)
+				}
+				assertComplete(t, colors, derivedKeys...)
+
+				bg := colors[models.BackgroundKey]
+				if isDark := lightness(t, bg) < 0.5; isDark != dark {
+					t.Errorf("%s %s dark=%v: background %s", h, s, dark, bg)
+				}
+				for _, key := range chromaticKeys {
+					if ratio := contrast(t, colors[key], bg); ratio < color.ContrastAA {
+						t.Errorf("%s %s dark=%v: %s has contrast %.2f", h, s, dark, key, ratio)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateMultipleSeeds(t *testing.T) {
+	seeds := []color.RGB{color.MustParse("#ff0000"), color.MustParse("#0000ff")}
+	colors, err := Generate(GenerateOptions{Seeds: seeds, Harmony: Analogous, Dark: true, Seed: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Every hue is pulled towards one of the seeds' hues, not the harmony
+	targets := []float64{seeds[0].OKLCH().H, seeds[1].OKLCH().H}
+	for _, key := range chromaticKeys {
+		want := pullHue(ansiHues[key], targets)
+		got := oklch(t, colors[key]).H
+		if d := hueDelta(got, want); d < -hueJitter-3 || d > hueJitter+3 {
+			t.Errorf("%s hue %.1f, want %.1f within jitter", key, got, want)
+		}
+	}
+}
+
+func TestGenerateTintedMonochrome(t *testing.T) {
+	seed := color.MustParse("#268bd2")
+	colors, err := Generate(GenerateOptions{Seeds: []color.RGB{seed}, Harmony: TintedMonochrome, Dark: true, Seed: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range chromaticKeys {
+		if d := hueDelta(oklch(t, colors[key]).H, seed.OKLCH().H); d < -10 || d > 10 {
+			t.Errorf("%s has hue %.1f, want the seed's %.1f", key, oklch(t, colors[key]).H, seed.OKLCH().H)
+		}
+	}
+}
+
+func TestGenerateNoSeeds(t *testing.T) {
+	if _, err := Generate(GenerateOptions{Harmony: Triadic}); !errors.Is(err, ErrNoSeeds) {
+		t.Errorf("got %v, want ErrNoSeeds", err)
+	}
+}
+
+func TestHarmonyValid(t *testing.T) {
+	for _, h := range harmonies {
+		if !h.Valid() {
+			t.Errorf("%s is not valid", h)
+		}
+	}
+	if Harmony("tetradic").Valid() {
+		t.Error("tetradic is valid")
+	}
+}
+
+func TestHueDelta(t *testing.T) {
+	tests := []struct {
+		a, b, want float64
+	}{
+		{0, 90, 90},
+		{90, 0, -90},
+		{350, 10, 20},
+		{10, 350, -20},
+		{0, 180, 180},
+		{180, 0, 180},
+		{30, 30, 0},
+	}
+
+	for _, tt := range tests {
+		if got := hueDelta(tt.a, tt.b); !near(got, tt.want, 1e-9) {
+			t.Errorf("hueDelta(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestPullHue(t *testing.T) {
+	tests := []struct {
+		h       float64
+		targets []float64
+		want    float64
+	}{
+		{100, []float64{120}, 110},
+		{100, []float64{60, 120}, 110},
+		// Across 0, towards the closer target
+		{350, []float64{10, 200}, 0},
+		{10, []float64{330}, 350},
+	}
+
+	for _, tt := range tests {
+		if got := pullHue(tt.h, tt.targets); !near(got, tt.want, 1e-9) {
+			t.Errorf("pullHue(%v, %v) = %v, want %v", tt.h, tt.targets, got, tt.want)
+		}
+	}
+}
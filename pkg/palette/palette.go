@@ -0,0 +1,37 @@
+// Package palette implements scheme-level analyses and transformations on
+// top of pkg/color.
+package palette
+
+import (
+	"fmt"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// parseSlot parses the color stored under key.
+func parseSlot(scheme models.ColorScheme, key string) (color.RGB, error) {
+	value, ok := scheme.Colors[key]
+	if !ok {
+		return color.RGB{}, fmt.Errorf("missing color %q", key)
+	}
+
+	c, err := color.Parse(value)
+	if err != nil {
+		return color.RGB{}, fmt.Errorf("color %q: %w", key, err)
+	}
+	return c, nil
+}
+
+// backgroundAndForeground parses the scheme's background and foreground.
+func backgroundAndForeground(scheme models.ColorScheme) (color.RGB, color.RGB, error) {
+	bg, err := parseSlot(scheme, scheme.BackgroundSlot())
+	if err != nil {
+		return color.RGB{}, color.RGB{}, err
+	}
+	fg, err := parseSlot(scheme, scheme.ForegroundSlot())
+	if err != nil {
+		return color.RGB{}, color.RGB{}, err
+	}
+	return bg, fg, nil
+}
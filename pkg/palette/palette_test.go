@@ -0,0 +1,70 @@
+package palette
+
+import (
+	"maps"
+	"math"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// solarizedDark is a complete scheme shared by the tests.
+var solarizedDark = map[string]string{
+	"background":    "#002b36",
+	"foreground":    "#839496",
+	"black":         "#073642",
+	"red":           "#dc322f",
+	"green":         "#859900",
+	"yellow":        "#b58900",
+	"blue":          "#268bd2",
+	"magenta":       "#d33682",
+	"cyan":          "#2aa198",
+	"white":         "#eee8d5",
+	"brightBlack":   "#002b36",
+	"brightRed":     "#cb4b16",
+	"brightGreen":   "#586e75",
+	"brightYellow":  "#657b83",
+	"brightBlue":    "#839496",
+	"brightMagenta": "#6c71c4",
+	"brightCyan":    "#93a1a1",
+	"brightWhite":   "#fdf6e3",
+}
+
+func newScheme(colors map[string]string) models.ColorScheme {
+	return models.ColorScheme{ID: "scheme", Name: "Solarized Dark", Colors: maps.Clone(colors)}
+}
+
+func near(a, b, tolerance float64) bool {
+	return math.Abs(a-b) <= tolerance
+}
+
+// contrast is the WCAG contrast ratio between two stored colors.
+func contrast(t *testing.T, a, b string) float64 {
+	t.Helper()
+	ca, err := color.Parse(a)
+	if err != nil {
+		t.Fatalf("parse %q: %v", a, err)
+	}
+	cb, err := color.Parse(b)
+	if err != nil {
+		t.Fatalf("parse %q: %v", b, err)
+	}
+	return color.ContrastRatio(ca, cb)
+}
+
+// assertComplete fails unless colors holds a parsable value for every ANSI
+// key and the given extra keys.
+func assertComplete(t *testing.T, colors map[string]string, extra ...string) {
+	t.Helper()
+	for _, key := range append(append([]string{}, models.ANSIColorKeys...), extra...) {
+		value, ok := colors[key]
+		if !ok {
+			t.Errorf("missing %s", key)
+			continue
+		}
+		if _, err := color.Parse(value); err != nil {
+			t.Errorf("%s: %v", key, err)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package palette
+
+import (
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// Schemes closer than this mean CIEDE2000 distance are near-duplicates,
+// the difference is barely perceptible side by side.
+const NearDuplicateDistance = 2.0
+
+// Schemes sharing fewer comparable slots than this are not compared.
+const minCommonSlots = 8
+
+// Features returns the CIELAB value of the background, the foreground and
+// every ANSI color of the scheme. Background and foreground are resolved
+// as in Accessibility, so a scheme relying on black and white compares
+// equal to one that spells them out.
+func Features(scheme models.ColorScheme) (*models.SchemeFeatures, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	features := &models.SchemeFeatures{
+		SchemeID: scheme.ID,
+		Slots:    make(map[string][3]float64, len(models.ANSIColorKeys)+2),
+	}
+	add := func(key string, c color.RGB) {
+		lab := c.Lab()
+		features.Slots[key] = [3]float64{round(lab.L, 3), round(lab.A, 3), round(lab.B, 3)}
+	}
+
+	add(models.BackgroundKey, bg)
+	add(models.ForegroundKey, fg)
+	for _, key := range models.ANSIColorKeys {
+		if c, err := parseSlot(scheme, key); err == nil {
+			add(key, c)
+		}
+	}
+	features.BackgroundL = features.Slots[models.BackgroundKey][0]
+	return features, nil
+}
+
+// Distance is the mean CIEDE2000 difference over the slots both schemes
+// define. It reports false when they share too few slots to compare.
+func Distance(a, b models.SchemeFeatures) (float64, bool) {
+	total, n := 0.0, 0
+	for key, x := range a.Slots {
+		y, ok := b.Slots[key]
+		if !ok {
+			continue
+		}
+		total += color.DeltaE2000(color.Lab{L: x[0], A: x[1], B: x[2]}, color.Lab{L: y[0], A: y[1], B: y[2]})
+		n++
+	}
+	if n < minCommonSlots {
+		return 0, false
+	}
+	return round(total/float64(n), 3), true
+}
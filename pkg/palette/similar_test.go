@@ -0,0 +1,88 @@
+package palette
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+func features(t *testing.T, colors map[string]string) models.SchemeFeatures {
+	t.Helper()
+	f, err := Features(newScheme(colors))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *f
+}
+
+func TestFeatures(t *testing.T) {
+	f := features(t, solarizedDark)
+	if len(f.Slots) != len(models.ANSIColorKeys)+2 {
+		t.Errorf("got %d slots, want %d", len(f.Slots), len(models.ANSIColorKeys)+2)
+	}
+	if f.SchemeID != "scheme" {
+		t.Errorf("scheme id %q", f.SchemeID)
+	}
+
+	// #002b36 in CIELAB (D65), rounded to three places
+	bg := f.Slots[models.BackgroundKey]
+	if bg != [3]float64{15.455, -9.351, -11.089} {
+		t.Errorf("background %v", bg)
+	}
+	if f.BackgroundL != bg[0] {
+		t.Errorf("BackgroundL %v, want %v", f.BackgroundL, bg[0])
+	}
+}
+
+// A scheme relying on black and white for its background and foreground
+// has the same features as one spelling them out.
+func TestFeaturesResolveSlots(t *testing.T) {
+	implicit := maps.Clone(solarizedDark)
+	delete(implicit, models.BackgroundKey)
+	delete(implicit, models.ForegroundKey)
+	explicit := maps.Clone(implicit)
+	explicit[models.BackgroundKey] = implicit["black"]
+	explicit[models.ForegroundKey] = implicit["white"]
+
+	d, ok := Distance(features(t, implicit), features(t, explicit))
+	if !ok || d != 0 {
+		t.Errorf("got %v, %v, want 0", d, ok)
+	}
+}
+
+func TestDistance(t *testing.T) {
+	a := features(t, solarizedDark)
+
+	if d, ok := Distance(a, a); !ok || d != 0 {
+		t.Errorf("distance to itself %v, %v", d, ok)
+	}
+
+	// Nudging one slot barely moves the scheme
+	nudged := maps.Clone(solarizedDark)
+	nudged["red"] = "#d83330"
+	d, ok := Distance(a, features(t, nudged))
+	if !ok || d <= 0 || d >= NearDuplicateDistance {
+		t.Errorf("nudged scheme at %v, %v, want a near-duplicate", d, ok)
+	}
+
+	variant, err := Variant(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := features(t, variant.Colors)
+	d, ok = Distance(a, b)
+	if !ok || d < 10 {
+		t.Errorf("light variant at %v, %v, want far away", d, ok)
+	}
+	if r, _ := Distance(b, a); r != d {
+		t.Errorf("distance is not symmetric: %v and %v", d, r)
+	}
+}
+
+func TestDistanceTooFewSlots(t *testing.T) {
+	few := map[string]string{"background": "#002b36", "foreground": "#839496", "red": "#dc322f"}
+	if _, ok := Distance(features(t, solarizedDark), features(t, few)); ok {
+		t.Error("schemes sharing 3 slots were compared")
+	}
+}
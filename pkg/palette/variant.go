@@ -0,0 +1,137 @@
+package palette
+
+import (
+	"math"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// Categories assigned to generated variants.
+const (
+	CategoryDark  = "Dark"
+	CategoryLight = "Light"
+)
+
+// variantTarget is the OKLCH lightness a generated variant maps its
+// background and foreground to, tinted or not. Other chromatic colors use a
+// narrower band (near for colors close to the background, far for those
+// close to the foreground) so hues such as yellow stay recognizable.
+type variantTarget struct {
+	bg, fg    float64
+	near, far float64
+}
+
+var variantTargets = map[string]variantTarget{
+	CategoryLight: {bg: 0.97, fg: 0.25, near: 0.72, far: 0.42},
+	CategoryDark:  {bg: 0.22, fg: 0.92, near: 0.62, far: 0.88},
+}
+
+// Colors below this OKLCH chroma are treated as neutrals.
+const neutralChroma = 0.03
+
+// IsDark reports whether the scheme's background is in the dark half of the
+// OKLCH lightness range.
+func IsDark(scheme models.ColorScheme) (bool, error) {
+	bg, err := parseSlot(scheme, scheme.BackgroundSlot())
+	if err != nil {
+		return false, err
+	}
+	return bg.OKLCH().L < 0.5, nil
+}
+
+// Variant returns the light companion of a dark scheme or the dark companion
+// of a light one. Lightness is inverted by a linear map sending the
+// background and foreground to typical values for the target, hue and
+// chroma are kept (see variantTarget), and every color is then pushed away
+// from the new background until it has at least the contrast it had
+// before, between AA-large and AA. The result always has explicit background and foreground
+// keys and its category set to Light or Dark. ID and author are left to the
+// caller.
+func Variant(scheme models.ColorScheme) (models.ColorScheme, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return models.ColorScheme{}, err
+	}
+
+	bgL, fgL := bg.OKLCH().L, fg.OKLCH().L
+	category := CategoryLight
+	if bgL >= 0.5 {
+		category = CategoryDark
+	}
+	target := variantTargets[category]
+
+	// Neutrals map bgL to target.bg and fgL to target.fg, chromatic colors
+	// map the same range onto [near, far]. A scheme whose foreground and
+	// background share a lightness falls back to plain inversion.
+	span := fgL - bgL
+	if math.Abs(span) < 0.05 {
+		span = math.Copysign(1, span)
+	}
+	remap := func(c color.RGB, neutral bool) color.RGB {
+		o := c.OKLCH()
+		t := (o.L - bgL) / span
+		if neutral || o.C < neutralChroma {
+			o.L = target.bg + t*(target.fg-target.bg)
+		} else {
+			o.L = target.near + t*(target.far-target.near)
+		}
+		o.L = math.Max(0, math.Min(1, o.L))
+		return o.ClipToGamut()
+	}
+
+	// A tinted background such as Solarized's still has to land on a
+	// typical background lightness
+	newBg := remap(bg, true)
+	variant := models.ColorScheme{
+		Name:     scheme.Name + " " + category,
+		Category: category,
+		Colors:   make(map[string]string, len(scheme.Colors)+2),
+	}
+
+	for key, value := range scheme.Colors {
+		c, err := color.Parse(value)
+		if err != nil {
+			variant.Colors[key] = value
+			continue
+		}
+
+		// Colors that were meant to blend into the background keep doing
+		// so, the others keep their contrast
+		original := color.ContrastRatio(c, bg)
+		inverted := remap(c, original < 1.5)
+		if original >= 1.5 {
+			minContrast := math.Max(color.ContrastAALarge, math.Min(color.ContrastAA, original))
+			inverted = ensureContrast(inverted, newBg, minContrast)
+		}
+		variant.Colors[key] = inverted.Hex()
+	}
+
+	variant.Colors[models.BackgroundKey] = newBg.Hex()
+	variant.Colors[models.ForegroundKey] = ensureContrast(remap(fg, true), newBg, math.Min(color.ContrastAAA, color.ContrastRatio(fg, bg))).Hex()
+
+	return variant, nil
+}
+
+// ensureContrast moves c's lightness away from bg in small steps until it
+// reaches minContrast or hits black or white. Contrast is measured on the
+// 8-bit colors, so the stored hex values still reach it.
+func ensureContrast(c, bg color.RGB, minContrast float64) color.RGB {
+	o := c.OKLCH()
+	step := 0.01
+	if bg.OKLCH().L > 0.5 {
+		step = -step
+	}
+
+	c, bg = to8Bit(c), to8Bit(bg)
+	for color.ContrastRatio(c, bg) < minContrast && o.L > 0 && o.L < 1 {
+		o.L = math.Max(0, math.Min(1, o.L+step))
+		c = to8Bit(o.ClipToGamut())
+	}
+	return c
+}
+
+// to8Bit rounds c to the color its hex form stands for.
+func to8Bit(c color.RGB) color.RGB {
+	return color.FromRGB8(c.RGB8())
+}
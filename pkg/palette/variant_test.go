@@ -0,0 +1,166 @@
+package palette
+
+import (
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+func TestVariantOfDark(t *testing.T) {
+	variant, err := Variant(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if variant.Category != CategoryLight || variant.Name != "Solarized Dark Light" {
+		t.Errorf("got %q, category %s", variant.Name, variant.Category)
+	}
+	if len(variant.Colors) != len(solarizedDark) {
+		t.Errorf("got %d colors, want %d", len(variant.Colors), len(solarizedDark))
+	}
+
+	bg := variant.Colors[models.BackgroundKey]
+	if l := lightness(t, bg); !near(l, variantTargets[CategoryLight].bg, 0.02) {
+		t.Errorf("background %s has lightness %.3f", bg, l)
+	}
+	assertVariantContrast(t, solarizedDark, variant.Colors)
+}
+
+func TestVariantOfLight(t *testing.T) {
+	light := map[string]string{
+		"background": "#fdf6e3",
+		"foreground": "#657b83",
+		"black":      "#073642",
+		"red":        "#dc322f",
+		"green":      "#859900",
+		"yellow":     "#b58900",
+		"blue":       "#268bd2",
+		"magenta":    "#d33682",
+		"cyan":       "#2aa198",
+		"white":      "#eee8d5",
+	}
+
+	variant, err := Variant(newScheme(light))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant.Category != CategoryDark {
+		t.Errorf("category %s, want Dark", variant.Category)
+	}
+	if l := lightness(t, variant.Colors[models.BackgroundKey]); !near(l, variantTargets[CategoryDark].bg, 0.02) {
+		t.Errorf("background lightness %.3f", l)
+	}
+	assertVariantContrast(t, light, variant.Colors)
+}
+
+// assertVariantContrast checks that colors readable on the original
+// background are still readable on the variant's: at least AA-large, or
+// their original contrast if that was lower than AA.
+func assertVariantContrast(t *testing.T, original, variant map[string]string) {
+	t.Helper()
+	bg, newBg := original[models.BackgroundKey], variant[models.BackgroundKey]
+	for key, value := range original {
+		if key == models.BackgroundKey {
+			continue
+		}
+		before := contrast(t, value, bg)
+		if before < 1.5 {
+			continue
+		}
+		want := max(color.ContrastAALarge, min(color.ContrastAA, before))
+		if key == models.ForegroundKey {
+			want = min(color.ContrastAAA, before)
+		}
+		if after := contrast(t, variant[key], newBg); after < want-0.01 {
+			t.Errorf("%s: contrast %.2f on the variant, want at least %.2f", key, after, want)
+		}
+	}
+}
+
+func TestVariantKeepsHues(t *testing.T) {
+	variant, err := Variant(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"red", "green", "blue", "magenta", "cyan"} {
+		before, after := oklch(t, solarizedDark[key]), oklch(t, variant.Colors[key])
+		if d := hueDelta(before.H, after.H); d < -10 || d > 10 {
+			t.Errorf("%s hue moved from %.1f to %.1f", key, before.H, after.H)
+		}
+	}
+}
+
+func TestVariantRoundTrip(t *testing.T) {
+	light, err := Variant(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dark, err := Variant(light)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dark.Category != CategoryDark {
+		t.Errorf("category %s, want Dark", dark.Category)
+	}
+}
+
+func TestVariantUnparsable(t *testing.T) {
+	colors := map[string]string{"background": "#000000", "foreground": "#ffffff", "comment": "italic"}
+	variant, err := Variant(newScheme(colors))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if variant.Colors["comment"] != "italic" {
+		t.Errorf("comment = %q, want it copied", variant.Colors["comment"])
+	}
+
+	if _, err := Variant(newScheme(map[string]string{"red": "#ff0000"})); err == nil {
+		t.Error("want an error without a background")
+	}
+}
+
+func TestIsDark(t *testing.T) {
+	tests := []struct {
+		colors map[string]string
+		want   bool
+	}{
+		{solarizedDark, true},
+		{map[string]string{"background": "#fdf6e3"}, false},
+		// black stands in for a missing background
+		{map[string]string{"black": "#000000"}, true},
+		{map[string]string{"black": "#ffffff"}, false},
+	}
+
+	for _, tt := range tests {
+		got, err := IsDark(newScheme(tt.colors))
+		if err != nil || got != tt.want {
+			t.Errorf("IsDark(%v) = %v, %v, want %v", tt.colors, got, err, tt.want)
+		}
+	}
+}
+
+func TestEnsureContrast(t *testing.T) {
+	tests := []struct {
+		c, bg string
+		min   float64
+	}{
+		{"#333333", "#000000", color.ContrastAA},
+		{"#cccccc", "#ffffff", color.ContrastAA},
+		{"#b58900", "#fdf6e3", color.ContrastAA},
+		{"#586e75", "#002b36", color.ContrastAAA},
+	}
+
+	for _, tt := range tests {
+		got := ensureContrast(color.MustParse(tt.c), color.MustParse(tt.bg), tt.min)
+		// Measured on the hex value, as it is stored
+		if ratio := contrast(t, got.Hex(), tt.bg); ratio < tt.min {
+			t.Errorf("ensureContrast(%s, %s) = %s with contrast %.3f", tt.c, tt.bg, got.Hex(), ratio)
+		}
+	}
+
+	// Colors that already pass are left alone
+	if got := ensureContrast(color.MustParse("#ffffff"), color.MustParse("#000000"), color.ContrastAA); got.Hex() != "#ffffff" {
+		t.Errorf("got %s", got.Hex())
+	}
+}
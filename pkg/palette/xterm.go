@@ -0,0 +1,132 @@
+package palette
+
+import (
+	"sort"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+// cubeCorners are the slots placed at the corners of the 6x6x6 cube, by
+// red, green and blue bit. Background and foreground take the black and
+// white corners so the cube spans the scheme's own range.
+var cubeCorners = [8]string{
+	models.BackgroundKey, "blue", "green", "cyan",
+	"red", "magenta", "yellow", models.ForegroundKey,
+}
+
+// Xterm maps every color of the scheme to its nearest xterm-256 cube or
+// grayscale color and to the nearest default 16 and 8 system colors, and
+// builds a 256-color table tinted to the scheme. In that table the system
+// colors are the scheme's ANSI colors, the cube is interpolated in CIELAB
+// between the scheme's background, six hues and foreground, and the
+// grayscale ramp runs from background to foreground.
+func Xterm(scheme models.ColorScheme) (*models.XtermReport, error) {
+	bg, fg, err := backgroundAndForeground(scheme)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.XtermReport{SchemeID: scheme.ID}
+	for _, key := range xtermKeyOrder(scheme) {
+		c, err := parseSlot(scheme, key)
+		if err != nil {
+			continue
+		}
+		i256, d := color.NearestXterm(c, color.XtermCubeStart, len(color.Xterm256))
+		i16, _ := color.NearestXterm(c, 0, 16)
+		i8, _ := color.NearestXterm(c, 0, 8)
+		report.Mappings = append(report.Mappings, models.XtermMapping{
+			Key:           key,
+			Color:         c.Hex(),
+			Xterm256:      i256,
+			Xterm256Color: color.Xterm256[i256].Hex(),
+			DeltaE:        round(d, 2),
+			ANSI16:        i16,
+			ANSI8:         i8,
+		})
+	}
+
+	table := make([]color.Lab, 256)
+	for i, key := range models.ANSIColorKeys {
+		table[i] = slotOrDefault(scheme, key, i).Lab()
+	}
+
+	var corners [8]color.Lab
+	for i, key := range cubeCorners {
+		switch key {
+		case models.BackgroundKey:
+			corners[i] = bg.Lab()
+		case models.ForegroundKey:
+			corners[i] = fg.Lab()
+		default:
+			corners[i] = table[ansiIndex(key)]
+		}
+	}
+
+	for i := range 216 {
+		r, g, b := float64(i/36)/5, float64(i/6%6)/5, float64(i%6)/5
+		// Trilinear interpolation, one axis at a time
+		c0 := lerpLab(lerpLab(corners[0], corners[4], r), lerpLab(corners[2], corners[6], r), g)
+		c1 := lerpLab(lerpLab(corners[1], corners[5], r), lerpLab(corners[3], corners[7], r), g)
+		table[color.XtermCubeStart+i] = lerpLab(c0, c1, b)
+	}
+
+	for i := range 24 {
+		table[color.XtermGrayStart+i] = lerpLab(bg.Lab(), fg.Lab(), float64(i+1)/25)
+	}
+
+	report.Palette256 = make([]string, len(table))
+	for i, lab := range table {
+		report.Palette256[i] = lab.RGB().Clamp().Hex()
+	}
+	return report, nil
+}
+
+// xtermKeyOrder lists the ANSI keys in index order followed by the
+// scheme's other keys sorted by name.
+func xtermKeyOrder(scheme models.ColorScheme) []string {
+	keys := make([]string, 0, len(scheme.Colors))
+	ansi := make(map[string]bool, len(models.ANSIColorKeys))
+	for _, key := range models.ANSIColorKeys {
+		ansi[key] = true
+		if _, ok := scheme.Colors[key]; ok {
+			keys = append(keys, key)
+		}
+	}
+
+	var rest []string
+	for key := range scheme.Colors {
+		if !ansi[key] {
+			rest = append(rest, key)
+		}
+	}
+	sort.Strings(rest)
+	return append(keys, rest...)
+}
+
+// slotOrDefault returns the scheme's color for key, or the default xterm
+// color at index when it is missing or unparsable.
+func slotOrDefault(scheme models.ColorScheme, key string, index int) color.RGB {
+	if c, err := parseSlot(scheme, key); err == nil {
+		return c
+	}
+	return color.Xterm256[index]
+}
+
+func ansiIndex(key string) int {
+	for i, k := range models.ANSIColorKeys {
+		if k == key {
+			return i
+		}
+	}
+	return -1
+}
+
+func lerpLab(a, b color.Lab, t float64) color.Lab {
+	return color.Lab{
+		L: a.L + (b.L-a.L)*t,
+		A: a.A + (b.A-a.A)*t,
+		B: a.B + (b.B-a.B)*t,
+	}
+}
@@ -0,0 +1,132 @@
+package palette
+
+import (
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+)
+
+func TestXtermMappings(t *testing.T) {
+	scheme := newScheme(map[string]string{
+		"background":  "#000000",
+		"foreground":  "#ffffff",
+		"red":         "#ff0000",
+		"blue":        "#5f87af",
+		"brightBlack": "#808080",
+		"comment":     "#8a8a8a",
+		"accent":      "not a color",
+	})
+
+	report, err := Xterm(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		key      string
+		xterm256 int
+		ansi16   int
+		ansi8    int
+	}{
+		// ANSI keys first, in index order, then the others by name
+		{"red", 196, 9, 1},
+		{"blue", 67, 12, 6},
+		{"brightBlack", 244, 8, 7},
+		{"background", 16, 0, 0},
+		{"comment", 245, 8, 7},
+		{"foreground", 231, 15, 7},
+	}
+	if len(report.Mappings) != len(want) {
+		t.Fatalf("got %d mappings, want %d: %+v", len(report.Mappings), len(want), report.Mappings)
+	}
+	for i, w := range want {
+		m := report.Mappings[i]
+		if m.Key != w.key || m.Xterm256 != w.xterm256 || m.ANSI16 != w.ansi16 || m.ANSI8 != w.ansi8 {
+			t.Errorf("mapping %d = %s %d/%d/%d, want %s %d/%d/%d", i, m.Key, m.Xterm256, m.ANSI16, m.ANSI8, w.key, w.xterm256, w.ansi16, w.ansi8)
+		}
+		if m.Xterm256Color != color.Xterm256[m.Xterm256].Hex() {
+			t.Errorf("%s: color %s for index %d", m.Key, m.Xterm256Color, m.Xterm256)
+		}
+	}
+	// Exact cube colors have no error
+	if report.Mappings[0].DeltaE != 0 || report.Mappings[1].DeltaE != 0 {
+		t.Errorf("exact matches have delta %v and %v", report.Mappings[0].DeltaE, report.Mappings[1].DeltaE)
+	}
+}
+
+func TestXtermPalette(t *testing.T) {
+	report, err := Xterm(newScheme(solarizedDark))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := report.Palette256
+	if len(p) != 256 {
+		t.Fatalf("got %d colors", len(p))
+	}
+	// System colors are the scheme's own
+	for i, key := range models.ANSIColorKeys {
+		if p[i] != solarizedDark[key] {
+			t.Errorf("color %d = %s, want %s %s", i, p[i], key, solarizedDark[key])
+		}
+	}
+
+	// The cube's black and white corners are the background and foreground,
+	// the others its ANSI hues
+	corners := map[int]string{
+		color.XtermCubeStart:       solarizedDark["background"],
+		color.XtermCubeStart + 215: solarizedDark["foreground"],
+		color.XtermCubeStart + 180: solarizedDark["red"],
+		color.XtermCubeStart + 30:  solarizedDark["green"],
+		color.XtermCubeStart + 5:   solarizedDark["blue"],
+		color.XtermCubeStart + 210: solarizedDark["yellow"],
+	}
+	for i, want := range corners {
+		if !sameColorWithin(t, p[i], want, 1) {
+			t.Errorf("color %d = %s, want %s", i, p[i], want)
+		}
+	}
+
+	// The grayscale ramp runs from background to foreground, excluding both
+	bg, fg := lightness(t, solarizedDark["background"]), lightness(t, solarizedDark["foreground"])
+	prev := bg
+	for i := color.XtermGrayStart; i < 256; i++ {
+		l := lightness(t, p[i])
+		if l <= prev-1e-3 || l >= fg {
+			t.Errorf("gray %d = %s is out of order", i, p[i])
+		}
+		prev = l
+	}
+}
+
+func TestXtermMissingColors(t *testing.T) {
+	report, err := Xterm(newScheme(map[string]string{"background": "#101010", "foreground": "#f0f0f0", "red": "bad"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Missing and unparsable ANSI colors take the xterm defaults
+	for i := range 16 {
+		if got, want := report.Palette256[i], color.Xterm256[i].Hex(); got != want {
+			t.Errorf("color %d = %s, want the default %s", i, got, want)
+		}
+	}
+
+	if _, err := Xterm(newScheme(map[string]string{"red": "#ff0000"})); err == nil {
+		t.Error("want an error without a background")
+	}
+}
+
+// sameColorWithin reports whether two hex colors differ by at most
+// tolerance on each 8-bit channel.
+func sameColorWithin(t *testing.T, a, b string, tolerance int) bool {
+	t.Helper()
+	ra, ga, ba := color.MustParse(a).RGB8()
+	rb, gb, bb := color.MustParse(b).RGB8()
+	for _, d := range []int{int(ra) - int(rb), int(ga) - int(gb), int(ba) - int(bb)} {
+		if d < -tolerance || d > tolerance {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,174 @@
+package policy
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/config"
+)
+
+// bcrypt ignores everything past 72 bytes.
+const passwordMaxBytes = 72
+
+//go:embed common_passwords.txt
+var commonPasswords []byte
+
+// defaultReserved are names that clash with routes or could impersonate
+// staff.
+var defaultReserved = []string{
+	"admin", "administrator", "api", "auth", "color-schemes", "edit", "help",
+	"login", "logout", "me", "mod", "moderator", "new", "null", "register",
+	"root", "settings", "staff", "support", "system", "undefined",
+}
+
+type Policy struct {
+	usernameMin     int
+	usernameMax     int
+	usernamePattern *regexp.Regexp
+	reserved        map[string]struct{}
+	passwordMin     int
+	common          map[string]struct{}
+}
+
+func New(cfg config.PolicyConfig) (*Policy, error) {
+	p := &Policy{
+		usernameMin: cfg.UsernameMinLength,
+		usernameMax: cfg.UsernameMaxLength,
+		passwordMin: cfg.PasswordMinLength,
+		reserved:    make(map[string]struct{}),
+		common:      make(map[string]struct{}),
+	}
+
+	if p.usernameMin <= 0 {
+		p.usernameMin = 3
+	}
+	if p.usernameMax <= 0 {
+		p.usernameMax = 32
+	}
+	if p.passwordMin <= 0 {
+		p.passwordMin = 8
+	}
+
+	pattern := cfg.UsernamePattern
+	if pattern == "" {
+		pattern = `^[a-zA-Z0-9][a-zA-Z0-9_-]*$`
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid username pattern: %w", err)
+	}
+	p.usernamePattern = re
+
+	for _, name := range append(defaultReserved, cfg.ReservedUsernames...) {
+		p.reserved[strings.ToLower(name)] = struct{}{}
+	}
+
+	if err := p.loadCommon(bytes.NewReader(commonPasswords)); err != nil {
+		return nil, err
+	}
+	if cfg.CommonPasswordsFile != "" {
+		f, err := os.Open(cfg.CommonPasswordsFile)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if err := p.loadCommon(f); err != nil {
+			return nil, err
+		}
+	}
+
+	return p, nil
+}
+
+// ValidateUsername checks length, charset and reserved names. Uniqueness is
+// left to the caller since it needs the database.
+func (p *Policy) ValidateUsername(username string) []models.FieldError {
+	var errs []models.FieldError
+
+	n := utf8.RuneCountInString(username)
+	if n < p.usernameMin || n > p.usernameMax {
+		errs = append(errs, models.FieldError{
+			Field:   "username",
+			Code:    "length",
+			Message: fmt.Sprintf("Username must be between %d and %d characters", p.usernameMin, p.usernameMax),
+		})
+	}
+
+	if !p.usernamePattern.MatchString(username) {
+		errs = append(errs, models.FieldError{
+			Field:   "username",
+			Code:    "charset",
+			Message: "Username may only contain letters, digits, '-' and '_'",
+		})
+	}
+
+	if _, ok := p.reserved[strings.ToLower(username)]; ok {
+		errs = append(errs, models.FieldError{
+			Field:   "username",
+			Code:    "reserved",
+			Message: "Username is reserved",
+		})
+	}
+
+	return errs
+}
+
+func (p *Policy) ValidatePassword(password, username string) []models.FieldError {
+	var errs []models.FieldError
+
+	if utf8.RuneCountInString(password) < p.passwordMin {
+		errs = append(errs, models.FieldError{
+			Field:   "password",
+			Code:    "too_short",
+			Message: fmt.Sprintf("Password must be at least %d characters", p.passwordMin),
+		})
+	}
+
+	if len(password) > passwordMaxBytes {
+		errs = append(errs, models.FieldError{
+			Field:   "password",
+			Code:    "too_long",
+			Message: fmt.Sprintf("Password must be at most %d bytes", passwordMaxBytes),
+		})
+	}
+
+	if _, ok := p.common[strings.ToLower(password)]; ok {
+		errs = append(errs, models.FieldError{
+			Field:   "password",
+			Code:    "common",
+			Message: "Password is too common",
+		})
+	}
+
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		errs = append(errs, models.FieldError{
+			Field:   "password",
+			Code:    "contains_username",
+			Message: "Password must not contain the username",
+		})
+	}
+
+	return errs
+}
+
+// loadCommon reads one password per line. Blank lines and lines starting
+// with '#' are ignored.
+func (p *Policy) loadCommon(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		p.common[strings.ToLower(line)] = struct{}{}
+	}
+	return scanner.Err()
+}
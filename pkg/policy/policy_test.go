@@ -0,0 +1,151 @@
+package policy
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/config"
+)
+
+func newPolicy(t *testing.T, cfg config.PolicyConfig) *Policy {
+	t.Helper()
+	p, err := New(cfg)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return p
+}
+
+// codes returns the codes of errs in order.
+func codes(errs []models.FieldError) []string {
+	var c []string
+	for _, e := range errs {
+		c = append(c, e.Code)
+	}
+	return c
+}
+
+func TestValidateUsername(t *testing.T) {
+	p := newPolicy(t, config.PolicyConfig{})
+
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{"alice", nil},
+		{"a-b_c9", nil},
+		{"abc", nil},
+		{strings.Repeat("a", 32), nil},
+		{"ab", []string{"length"}},
+		{strings.Repeat("a", 33), []string{"length"}},
+		{"", []string{"length", "charset"}},
+		{"-alice", []string{"charset"}},
+		{"alice smith", []string{"charset"}},
+		{"alicé", []string{"charset"}},
+		{"admin", []string{"reserved"}},
+		// Reserved names are matched case-insensitively
+		{"Admin", []string{"reserved"}},
+		{"ROOT", []string{"reserved"}},
+	}
+
+	for _, tt := range tests {
+		if got := codes(p.ValidateUsername(tt.username)); !slices.Equal(got, tt.want) {
+			t.Errorf("ValidateUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestValidateUsernameConfig(t *testing.T) {
+	p := newPolicy(t, config.PolicyConfig{
+		UsernameMinLength: 5,
+		UsernameMaxLength: 8,
+		UsernamePattern:   `^[a-z]+$`,
+		ReservedUsernames: []string{"Palette"},
+	})
+
+	tests := []struct {
+		username string
+		want     []string
+	}{
+		{"alice", nil},
+		{"bob", []string{"length"}},
+		{"alexandra", []string{"length"}},
+		{"alice9", []string{"charset"}},
+		{"palette", []string{"reserved"}},
+		// Defaults stay reserved alongside configured names
+		{"admin", []string{"reserved"}},
+	}
+
+	for _, tt := range tests {
+		if got := codes(p.ValidateUsername(tt.username)); !slices.Equal(got, tt.want) {
+			t.Errorf("ValidateUsername(%q) = %v, want %v", tt.username, got, tt.want)
+		}
+	}
+}
+
+func TestNewInvalidPattern(t *testing.T) {
+	if _, err := New(config.PolicyConfig{UsernamePattern: "("}); err == nil {
+		t.Error("want an error")
+	}
+}
+
+func TestValidatePassword(t *testing.T) {
+	p := newPolicy(t, config.PolicyConfig{})
+
+	tests := []struct {
+		name     string
+		password string
+		username string
+		want     []string
+	}{
+		{"ok", "correct horse battery", "alice", nil},
+		{"minimum length", "x7#kq!2z", "alice", nil},
+		{"too short", "x7#kq!2", "alice", []string{"too_short"}},
+		// Length counts characters, not bytes
+		{"multibyte minimum", "ééééééé", "alice", []string{"too_short"}},
+		{"72 bytes", strings.Repeat("x", 72), "alice", nil},
+		// bcrypt ignores everything past 72 bytes
+		{"73 bytes", strings.Repeat("x", 73), "alice", []string{"too_long"}},
+		{"multibyte past 72 bytes", strings.Repeat("é", 37), "alice", []string{"too_long"}},
+		{"common", "password", "alice", []string{"common"}},
+		{"common, any case", "PassWord", "alice", []string{"common"}},
+		{"contains username", "xxalice99", "alice", []string{"contains_username"}},
+		{"contains username, any case", "xxALICE99", "Alice", []string{"contains_username"}},
+		{"no username given", "xxalice99", "", nil},
+		{"several problems", "alice", "alice", []string{"too_short", "contains_username"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := codes(p.ValidatePassword(tt.password, tt.username)); !slices.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCommonPasswordsFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "common.txt")
+	if err := os.WriteFile(path, []byte("# extra passwords\n\n  Tr0ub4dor&3  \n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPolicy(t, config.PolicyConfig{CommonPasswordsFile: path})
+	for _, password := range []string{"tr0ub4dor&3", "password"} {
+		if got := codes(p.ValidatePassword(password, "")); !slices.Equal(got, []string{"common"}) {
+			t.Errorf("ValidatePassword(%q) = %v, want [common]", password, got)
+		}
+	}
+	// Comment lines are not passwords
+	if got := p.ValidatePassword("# extra passwords", ""); len(got) != 0 {
+		t.Errorf("got %v", codes(got))
+	}
+
+	if _, err := New(config.PolicyConfig{CommonPasswordsFile: filepath.Join(t.TempDir(), "missing.txt")}); err == nil {
+		t.Error("missing file: want an error")
+	}
+}
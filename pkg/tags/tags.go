@@ -0,0 +1,79 @@
+// Package tags normalizes the free-form tags users put on color schemes so
+// that "Solarized Family", "solarized_family" and "#solarized-family" are
+// the same tag.
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MaxLength is the longest normalized tag.
+	MaxLength = 32
+	// MaxPerScheme bounds the number of tags on one scheme.
+	MaxPerScheme = 10
+)
+
+var (
+	ErrEmpty   = errors.New("tag is empty")
+	ErrTooLong = fmt.Errorf("tag is longer than %d characters", MaxLength)
+)
+
+// Normalize lowercases tag, drops a leading '#' and turns runs of spaces,
+// underscores, dots and hyphens into a single hyphen, trimmed at both ends.
+// The result may only contain ASCII letters, digits and hyphens.
+func Normalize(tag string) (string, error) {
+	tag = strings.TrimPrefix(strings.TrimSpace(tag), "#")
+
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(tag) {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9':
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		case r == '-', r == '_', r == '.', r == ' ', r == '\t':
+			hyphen = true
+		default:
+			return "", fmt.Errorf("tag %q contains %q, only letters, digits and hyphens are allowed", tag, r)
+		}
+	}
+
+	switch {
+	case b.Len() == 0:
+		return "", ErrEmpty
+	case b.Len() > MaxLength:
+		return "", ErrTooLong
+	}
+	return b.String(), nil
+}
+
+// NormalizeAll normalizes every tag and returns them deduplicated and
+// sorted. It fails on the first invalid tag or when more than MaxPerScheme
+// distinct tags remain.
+func NormalizeAll(list []string) ([]string, error) {
+	seen := make(map[string]bool, len(list))
+	out := make([]string, 0, len(list))
+	for _, tag := range list {
+		t, err := Normalize(tag)
+		if err != nil {
+			return nil, err
+		}
+		if !seen[t] {
+			seen[t] = true
+			out = append(out, t)
+		}
+	}
+
+	if len(out) > MaxPerScheme {
+		return nil, fmt.Errorf("at most %d tags are allowed", MaxPerScheme)
+	}
+	sort.Strings(out)
+	return out, nil
+}
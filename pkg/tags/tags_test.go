@@ -0,0 +1,106 @@
+package tags
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"Solarized Family", "solarized-family"},
+		{"solarized_family", "solarized-family"},
+		{"#solarized-family", "solarized-family"},
+		{"  #Solarized.Family  ", "solarized-family"},
+		{"dark", "dark"},
+		{"Base16", "base16"},
+		// Runs of separators collapse and are trimmed at both ends
+		{"high -_. contrast", "high-contrast"},
+		{"--retro--", "retro"},
+		{"_pastel\t", "pastel"},
+		{strings.Repeat("a", MaxLength), strings.Repeat("a", MaxLength)},
+		// The length cap applies to the normalized tag
+		{"#" + strings.Repeat("a", MaxLength) + "--", strings.Repeat("a", MaxLength)},
+	}
+
+	for _, tt := range tests {
+		got, err := Normalize(tt.in)
+		if err != nil {
+			t.Errorf("Normalize(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeInvalid(t *testing.T) {
+	tests := []struct {
+		in   string
+		want error
+	}{
+		{"", ErrEmpty},
+		{"#", ErrEmpty},
+		{" - _ ", ErrEmpty},
+		{strings.Repeat("a", MaxLength+1), ErrTooLong},
+		{strings.Repeat("ab-", 11) + "c", ErrTooLong},
+	}
+
+	for _, tt := range tests {
+		if _, err := Normalize(tt.in); !errors.Is(err, tt.want) {
+			t.Errorf("Normalize(%q) = %v, want %v", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestNormalizeRejectsCharacters(t *testing.T) {
+	for _, in := range []string{"c++", "dark/light", "##dark", "café", "ダーク", "a,b", "tag\nname"} {
+		if got, err := Normalize(in); err == nil {
+			t.Errorf("Normalize(%q) = %q, want an error", in, got)
+		}
+	}
+}
+
+func TestNormalizeAll(t *testing.T) {
+	got, err := NormalizeAll([]string{"Solarized Family", "dark", "solarized_family", "#solarized-family", "Dark"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"dark", "solarized-family"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got, err := NormalizeAll(nil); err != nil || got == nil || len(got) != 0 {
+		t.Errorf("NormalizeAll(nil) = %#v, %v, want an empty slice", got, err)
+	}
+}
+
+func TestNormalizeAllInvalid(t *testing.T) {
+	if _, err := NormalizeAll([]string{"dark", "c++"}); err == nil {
+		t.Error("want an error for an invalid tag")
+	}
+}
+
+func TestNormalizeAllCap(t *testing.T) {
+	var list []string
+	for i := range MaxPerScheme {
+		list = append(list, fmt.Sprintf("tag%d", i))
+	}
+
+	if _, err := NormalizeAll(list); err != nil {
+		t.Errorf("%d tags: %v", len(list), err)
+	}
+	// Duplicates don't count towards the cap
+	if _, err := NormalizeAll(append(list, "TAG0", "#tag1")); err != nil {
+		t.Errorf("%d tags with duplicates: %v", len(list), err)
+	}
+	if _, err := NormalizeAll(append(list, "one-more")); err == nil {
+		t.Errorf("%d tags: want an error", len(list)+1)
+	}
+}
@@ -0,0 +1,157 @@
+package totp
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The RFC 6238 SHA-1 test key, the ASCII string "12345678901234567890".
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 Appendix B. The RFC lists 8-digit codes, a 6-digit code is their
+// last six digits.
+var rfcVectors = []struct {
+	unix int64
+	code string
+}{
+	{59, "287082"},
+	{1111111109, "081804"},
+	{1111111111, "050471"},
+	{1234567890, "005924"},
+	{2000000000, "279037"},
+	{20000000000, "353130"},
+}
+
+func TestCode(t *testing.T) {
+	for _, tt := range rfcVectors {
+		got, err := Code(rfcSecret, Counter(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code: %v", err)
+		}
+		if got != tt.code {
+			t.Errorf("T=%d: got %s, want %s", tt.unix, got, tt.code)
+		}
+	}
+}
+
+func TestCodeLowercaseSecret(t *testing.T) {
+	got, err := Code(strings.ToLower(rfcSecret), Counter(time.Unix(59, 0)))
+	if err != nil || got != "287082" {
+		t.Errorf("got %q, %v", got, err)
+	}
+}
+
+func TestCodeInvalidSecret(t *testing.T) {
+	if _, err := Code("not base32!", 1); err == nil {
+		t.Error("want an error")
+	}
+}
+
+func TestCounter(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want int64
+	}{
+		{0, 0},
+		{29, 0},
+		{30, 1},
+		{59, 1},
+		{1111111109, 0x23523EC},
+		{20000000000, 0x27BC86AA},
+	}
+
+	for _, tt := range tests {
+		if got := Counter(time.Unix(tt.unix, 0)); got != tt.want {
+			t.Errorf("Counter(%d) = %d, want %d", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	step := Counter(now)
+
+	tests := []struct {
+		name string
+		code string
+		skew int
+		want bool
+		step int64
+	}{
+		{name: "current step", code: "050471", skew: 0, want: true, step: step},
+		{name: "spaces are ignored", code: "050 471", skew: 0, want: true, step: step},
+		{name: "wrong code", code: "050472", skew: 1, want: false},
+		{name: "too short", code: "05047", skew: 1, want: false},
+		{name: "8-digit code", code: "14050471", skew: 1, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Validate(rfcSecret, tt.code, now, tt.skew)
+			if ok != tt.want || (ok && got != tt.step) {
+				t.Errorf("got (%d, %v), want (%d, %v)", got, ok, tt.step, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	previous, err := Code(rfcSecret, Counter(now)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := Validate(rfcSecret, previous, now, 0); ok {
+		t.Error("previous step accepted without skew")
+	}
+	step, ok := Validate(rfcSecret, previous, now, 1)
+	if !ok || step != Counter(now)-1 {
+		t.Errorf("got (%d, %v), want the previous step", step, ok)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := encoding.DecodeString(secret)
+	if err != nil {
+		t.Fatalf("secret %q is not base32: %v", secret, err)
+	}
+	if len(key) != 20 {
+		t.Errorf("got a %d byte key, want 20", len(key))
+	}
+
+	other, _ := GenerateSecret()
+	if other == secret {
+		t.Error("two secrets are equal")
+	}
+}
+
+func TestURI(t *testing.T) {
+	u, err := url.Parse(URI("ColorScheme", "alice", rfcSecret))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/ColorScheme:alice" {
+		t.Errorf("got %s", u)
+	}
+	q := u.Query()
+	want := map[string]string{
+		"secret":    rfcSecret,
+		"issuer":    "ColorScheme",
+		"algorithm": "SHA1",
+		"digits":    "6",
+		"period":    "30",
+	}
+	for key, value := range want {
+		if q.Get(key) != value {
+			t.Errorf("%s = %q, want %q", key, q.Get(key), value)
+		}
+	}
+}
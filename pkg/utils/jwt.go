@@ -6,15 +6,28 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateToken(username, secretKey string) (string, error) {
+func GenerateToken(username, role, secretKey string) (string, error) {
 	claims := jwt.MapClaims{
 		"username": username,
+		"role":     role,
 		"exp":      time.Now().Add(24 * time.Hour).Unix(),
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(secretKey))
 }
 
+// GenerateMFAToken issues a short-lived token proving the password step of a
+// two-factor login succeeded. It must not be accepted as a session token.
+func GenerateMFAToken(username, secretKey string) (string, error) {
+	claims := jwt.MapClaims{
+		"username":    username,
+		"mfa_pending": true,
+		"exp":         time.Now().Add(5 * time.Minute).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(secretKey))
+}
+
 func ValidateToken(tokenString, secretKey string) (*jwt.Token, error) {
 	return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secretKey), nil
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+type CollectionRepository interface {
+	GetByOwner(ctx context.Context, owner string) ([]models.Collection, error)
+	GetById(ctx context.Context, id string) (*models.Collection, error)
+	Create(ctx context.Context, collection models.Collection) error
+	Update(ctx context.Context, collection models.Collection) error
+	Delete(ctx context.Context, id string) error
+}
+
+const collectionColumns = "c.id, c.name, c.description, c.owner, c.public, c.created_at, c.updated_at, " +
+	"ARRAY(SELECT scheme_id FROM collection_schemes WHERE collection_id = c.id ORDER BY position)"
+
+type collectionRepository struct {
+	db *sql.DB
+}
+
+func NewCollectionRepository(db *sql.DB) CollectionRepository {
+	return &collectionRepository{db: db}
+}
+
+func (r *collectionRepository) GetByOwner(ctx context.Context, owner string) ([]models.Collection, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+collectionColumns+" FROM collections c WHERE c.owner = $1 ORDER BY c.name", owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var collections []models.Collection
+	for rows.Next() {
+		var c models.Collection
+		if err := scanCollection(rows, &c); err != nil {
+			return nil, err
+		}
+		collections = append(collections, c)
+	}
+	return collections, rows.Err()
+}
+
+func (r *collectionRepository) GetById(ctx context.Context, id string) (*models.Collection, error) {
+	var c models.Collection
+	row := r.db.QueryRowContext(ctx, "SELECT "+collectionColumns+" FROM collections c WHERE c.id = $1", id)
+	if err := scanCollection(row, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *collectionRepository) Create(ctx context.Context, collection models.Collection) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx,
+		"INSERT INTO collections (id, name, description, owner, public) VALUES ($1, $2, $3, $4, $5)",
+		collection.ID, collection.Name, collection.Description, collection.Owner, collection.Public,
+	)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := saveCollectionSchemes(ctx, tx, collection); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Update replaces the collection's name, description, visibility and
+// schemes.
+func (r *collectionRepository) Update(ctx context.Context, collection models.Collection) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	res, err := tx.ExecContext(ctx,
+		"UPDATE collections SET name = $1, description = $2, public = $3, updated_at = CURRENT_TIMESTAMP WHERE id = $4",
+		collection.Name, collection.Description, collection.Public, collection.ID,
+	)
+	if err == nil {
+		err = expectAffected(res)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := saveCollectionSchemes(ctx, tx, collection); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *collectionRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM collections WHERE id = $1", id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// saveCollectionSchemes replaces the collection's schemes, numbering their
+// positions in SchemeIDs order.
+func saveCollectionSchemes(ctx context.Context, tx *sql.Tx, collection models.Collection) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM collection_schemes WHERE collection_id = $1", collection.ID)
+	if err != nil {
+		return err
+	}
+	for i, schemeID := range collection.SchemeIDs {
+		_, err := tx.ExecContext(ctx,
+			"INSERT INTO collection_schemes (collection_id, scheme_id, position) VALUES ($1, $2, $3)",
+			collection.ID, schemeID, i,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func scanCollection(row rowScanner, c *models.Collection) error {
+	return row.Scan(&c.ID, &c.Name, &c.Description, &c.Owner, &c.Public, &c.CreatedAt, &c.UpdatedAt, pq.Array(&c.SchemeIDs))
+}
@@ -3,18 +3,55 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
 
+	"github.com/lib/pq"
 	"github.com/nqvinh00/colorscheme/models"
 )
 
 type ColorSchemeRepository interface {
-	GetByAuthor(ctx context.Context, author string) ([]models.ColorScheme, error)
+	GetByAuthor(ctx context.Context, author string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	GetVisible(ctx context.Context, viewer string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	GetByOrganization(ctx context.Context, org string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
 	GetById(ctx context.Context, id string) (*models.ColorScheme, error)
-	Create(ctx context.Context, scheme models.ColorScheme) error
-	Update(ctx context.Context, scheme models.ColorScheme) error
-	Delete(ctx context.Context, id string) error
+	Create(ctx context.Context, scheme models.ColorScheme, editor string) error
+	CreateVariant(ctx context.Context, sourceID string, variant models.ColorScheme, editor string) error
+	Update(ctx context.Context, scheme models.ColorScheme, editor string) (int, error)
+	Delete(ctx context.Context, id string, version int) error
+	UpdateAuthor(ctx context.Context, id, author string) error
+	SetOwner(ctx context.Context, id, author string, org *string) error
+	SaveAccessibility(ctx context.Context, report models.AccessibilityReport) error
+	GetAccessibility(ctx context.Context, schemeID string) (*models.AccessibilityReport, error)
+	SaveFeatures(ctx context.Context, features models.SchemeFeatures) error
+	FindFeatures(ctx context.Context, viewer string, minBackgroundL, maxBackgroundL float64) ([]models.SchemeFeatures, error)
+	ListRevisions(ctx context.Context, schemeID string) ([]models.Revision, error)
+	GetRevision(ctx context.Context, schemeID string, revision int) (*models.Revision, error)
+	LatestRevision(ctx context.Context, schemeID string) (*int, error)
+	SetForkedRevision(ctx context.Context, id string, revision *int) error
+	GetAncestors(ctx context.Context, id string) ([]models.LineageEntry, error)
+	GetForks(ctx context.Context, id string) ([]models.LineageEntry, error)
+	ListTags(ctx context.Context, viewer, prefix string, limit int) ([]models.TagCount, error)
+	Star(ctx context.Context, id, username string) (int, error)
+	Unstar(ctx context.Context, id, username string) (int, error)
+	GetStarred(ctx context.Context, username string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	GetTrending(ctx context.Context, halfLife, window time.Duration, limit int) ([]models.TrendingColorScheme, error)
 }
 
+// maxLineageDepth bounds the ancestor walk.
+const maxLineageDepth = 100
+
+const (
+	schemeColumns = "s.id, s.name, s.author, s.organization, s.category, s.public, s.version, s.star_count, s.variant_id, s.duplicate_of, s.forked_from, s.forked_revision, a.score, " +
+		"m.background_luminance, m.foreground_contrast, m.average_chroma, m.temperature, m.traits, " +
+		"ARRAY(SELECT t.name FROM color_scheme_tags st JOIN tags t ON t.id = st.tag_id WHERE st.scheme_id = s.id ORDER BY t.name)"
+	revisionColumns = "scheme_id, revision, name, category, colors, editor, created_at"
+	schemeTables    = "color_schemes s " +
+		"LEFT JOIN color_scheme_accessibility a ON a.scheme_id = s.id " +
+		"LEFT JOIN color_scheme_metrics m ON m.scheme_id = s.id"
+)
+
 type colorSchemeRepository struct {
 	db *sql.DB
 }
@@ -23,8 +60,32 @@ func NewColorSchemeRepository(db *sql.DB) ColorSchemeRepository {
 	return &colorSchemeRepository{db: db}
 }
 
-func (r *colorSchemeRepository) GetByAuthor(ctx context.Context, author string) ([]models.ColorScheme, error) {
-	rows, err := r.db.QueryContext(ctx, "SELECT id, name, author, category FROM color_schemes WHERE author = $1", author)
+// GetByAuthor lists author's personal schemes. Schemes owned by an
+// organization are listed with it instead.
+func (r *colorSchemeRepository) GetByAuthor(ctx context.Context, author string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	return r.list(ctx, "s.author = $1 AND s.organization IS NULL", []any{author}, filter)
+}
+
+// GetVisible lists the public schemes and viewer's own that match filter.
+func (r *colorSchemeRepository) GetVisible(ctx context.Context, viewer string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	return r.list(ctx, visibleTo(1), []any{viewer}, filter)
+}
+
+// GetByOrganization lists the schemes owned by org that match filter.
+func (r *colorSchemeRepository) GetByOrganization(ctx context.Context, org string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	return r.list(ctx, "s.organization = $1", []any{org}, filter)
+}
+
+// GetStarred lists the schemes username has starred that they can still
+// see.
+func (r *colorSchemeRepository) GetStarred(ctx context.Context, username string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	return r.list(ctx, "s.id IN (SELECT scheme_id FROM color_scheme_stars WHERE username = $1) AND "+visibleTo(1), []any{username}, filter)
+}
+
+func (r *colorSchemeRepository) list(ctx context.Context, where string, args []any, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	query, args := applyFilter("SELECT "+schemeColumns+" FROM "+schemeTables+" WHERE "+where, args, filter)
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -33,103 +94,657 @@ func (r *colorSchemeRepository) GetByAuthor(ctx context.Context, author string)
 	var schemes []models.ColorScheme
 	for rows.Next() {
 		var s models.ColorScheme
-		if err := rows.Scan(&s.ID, &s.Name, &s.Author, &s.Category); err != nil {
+		if err := scanScheme(rows, &s); err != nil {
 			return nil, err
 		}
-		// Load colors for this scheme
-		colorRows, err := r.db.Query("SELECT color_key, color_value FROM color_scheme_colors WHERE scheme_id = $1", s.ID)
-		if err != nil {
+		schemes = append(schemes, s)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range schemes {
+		if schemes[i].Colors, err = r.loadColors(ctx, schemes[i].ID); err != nil {
 			return nil, err
 		}
-		s.Colors = make(map[string]string)
-		for colorRows.Next() {
-			var key, value string
-			if err := colorRows.Scan(&key, &value); err != nil {
-				colorRows.Close()
-				return nil, err
-			}
-			s.Colors[key] = value
-		}
-		colorRows.Close()
-		schemes = append(schemes, s)
 	}
 	return schemes, nil
 }
 
 func (r *colorSchemeRepository) GetById(ctx context.Context, id string) (*models.ColorScheme, error) {
-	rows := r.db.QueryRowContext(ctx, "SELECT id, name, author, category FROM color_schemes WHERE id = $1", id)
+	row := r.db.QueryRowContext(ctx, "SELECT "+schemeColumns+" FROM "+schemeTables+" WHERE s.id = $1", id)
 
 	var scheme models.ColorScheme
-	if err := rows.Scan(&scheme.ID, &scheme.Name, &scheme.Author, &scheme.Category); err != nil {
+	if err := scanScheme(row, &scheme); err != nil {
 		return nil, err
 	}
 
-	// Load colors for this scheme
-	colorRows, err := r.db.QueryContext(ctx, "SELECT color_key, color_value FROM color_scheme_colors WHERE scheme_id = $1", scheme.ID)
+	colors, err := r.loadColors(ctx, scheme.ID)
 	if err != nil {
 		return nil, err
 	}
-	scheme.Colors = make(map[string]string)
-	for colorRows.Next() {
-		var key, value string
-		if err := colorRows.Scan(&key, &value); err != nil {
-			colorRows.Close()
-			return nil, err
-		}
-		scheme.Colors[key] = value
-	}
-	colorRows.Close()
+	scheme.Colors = colors
 	return &scheme, nil
 }
 
-func (r *colorSchemeRepository) Create(ctx context.Context, scheme models.ColorScheme) error {
+func (r *colorSchemeRepository) Create(ctx context.Context, scheme models.ColorScheme, editor string) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	_, err = tx.ExecContext(ctx, "INSERT INTO color_schemes (id, name, author, category) VALUES ($1, $2, $3, $4)", scheme.ID, scheme.Name, scheme.Author, scheme.Category)
-	if err != nil {
+	if err := insertScheme(ctx, tx, scheme); err != nil {
 		tx.Rollback()
 		return err
 	}
-	for key, value := range scheme.Colors {
-		_, err := tx.ExecContext(ctx, "INSERT INTO color_scheme_colors (scheme_id, color_key, color_value) VALUES ($1, $2, $3)", scheme.ID, key, value)
-		if err != nil {
-			tx.Rollback()
-			return err
-		}
+	if _, err := insertRevision(ctx, tx, scheme, editor); err != nil {
+		tx.Rollback()
+		return err
 	}
 	return tx.Commit()
 }
 
-func (r *colorSchemeRepository) Update(ctx context.Context, scheme models.ColorScheme) error {
+// CreateVariant stores variant and links it with sourceID in both
+// directions.
+func (r *colorSchemeRepository) CreateVariant(ctx context.Context, sourceID string, variant models.ColorScheme, editor string) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		return err
 	}
-	_, err = tx.ExecContext(ctx, "UPDATE color_schemes SET name = $1, author = $2, category = $3 WHERE id = $4", scheme.Name, scheme.Author, scheme.Category, scheme.ID)
+	variant.VariantID = &sourceID
+	if err := insertScheme(ctx, tx, variant); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if _, err := insertRevision(ctx, tx, variant, editor); err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE color_schemes SET variant_id = $1, version = version + 1 WHERE id = $2", variant.ID, sourceID)
 	if err != nil {
 		tx.Rollback()
 		return err
 	}
+	return tx.Commit()
+}
+
+// Update replaces the scheme's name, category, colors and tags if it is
+// still at scheme.Version, bumps the version and records the new state as a
+// revision, whose number it returns. A stale version returns
+// sql.ErrNoRows. A scheme saved before revisions existed first gets its
+// current state recorded, so the update can be undone.
+func (r *colorSchemeRepository) Update(ctx context.Context, scheme models.ColorScheme, editor string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO color_scheme_revisions (scheme_id, revision, name, category, colors, editor)
+		SELECT s.id, 1, s.name, s.category,
+			COALESCE((SELECT json_object_agg(color_key, color_value) FROM color_scheme_colors WHERE scheme_id = s.id), '{}')::jsonb,
+			s.author
+		FROM color_schemes s
+		WHERE s.id = $1 AND NOT EXISTS (SELECT 1 FROM color_scheme_revisions WHERE scheme_id = $1)`,
+		scheme.ID,
+	)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx,
+		"UPDATE color_schemes SET name = $1, author = $2, category = $3, public = $4, version = version + 1 WHERE id = $5 AND version = $6",
+		scheme.Name, scheme.Author, scheme.Category, scheme.Public, scheme.ID, scheme.Version,
+	)
+	if err == nil {
+		err = expectAffected(res)
+	}
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
 	// Remove old colors
 	_, err = tx.ExecContext(ctx, "DELETE FROM color_scheme_colors WHERE scheme_id = $1", scheme.ID)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return 0, err
 	}
 	// Insert new colors
 	for key, value := range scheme.Colors {
 		_, err := tx.ExecContext(ctx, "INSERT INTO color_scheme_colors (scheme_id, color_key, color_value) VALUES ($1, $2, $3)", scheme.ID, key, value)
 		if err != nil {
 			tx.Rollback()
+			return 0, err
+		}
+	}
+	// The stored report describes the old colors, drop it so the next read
+	// rebuilds it if the service fails to save a new one
+	_, err = tx.ExecContext(ctx, "DELETE FROM color_scheme_accessibility WHERE scheme_id = $1", scheme.ID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := saveMetrics(ctx, tx, scheme); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if err := saveTags(ctx, tx, scheme); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	revision, err := insertRevision(ctx, tx, scheme, editor)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return revision, tx.Commit()
+}
+
+// Delete removes the scheme if it is still at version, any version for
+// models.AnyVersion. A stale version returns sql.ErrNoRows.
+func (r *colorSchemeRepository) Delete(ctx context.Context, id string, version int) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM color_schemes WHERE id = $1 AND ($2 = 0 OR version = $2)", id, version)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// SetOwner moves the scheme to author and org, nil for a personal scheme.
+func (r *colorSchemeRepository) SetOwner(ctx context.Context, id, author string, org *string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE color_schemes SET author = $1, organization = $2, version = version + 1 WHERE id = $3", author, org, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *colorSchemeRepository) UpdateAuthor(ctx context.Context, id, author string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE color_schemes SET author = $1, version = version + 1 WHERE id = $2", author, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *colorSchemeRepository) SaveAccessibility(ctx context.Context, report models.AccessibilityReport) error {
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO color_scheme_accessibility (scheme_id, score, min_contrast, failing_aa, report, computed_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scheme_id) DO UPDATE SET
+			score = EXCLUDED.score,
+			min_contrast = EXCLUDED.min_contrast,
+			failing_aa = EXCLUDED.failing_aa,
+			report = EXCLUDED.report,
+			computed_at = EXCLUDED.computed_at`,
+		report.SchemeID, report.Score, report.MinContrast, len(report.FailingAA), data, report.ComputedAt,
+	)
+	return err
+}
+
+func (r *colorSchemeRepository) GetAccessibility(ctx context.Context, schemeID string) (*models.AccessibilityReport, error) {
+	var data []byte
+	if err := r.db.QueryRowContext(ctx, "SELECT report FROM color_scheme_accessibility WHERE scheme_id = $1", schemeID).Scan(&data); err != nil {
+		return nil, err
+	}
+
+	var report models.AccessibilityReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+func (r *colorSchemeRepository) SaveFeatures(ctx context.Context, features models.SchemeFeatures) error {
+	slots, err := json.Marshal(features.Slots)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx,
+		`INSERT INTO color_scheme_features (scheme_id, background_l, slots)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (scheme_id) DO UPDATE SET
+			background_l = EXCLUDED.background_l,
+			slots = EXCLUDED.slots`,
+		features.SchemeID, features.BackgroundL, slots,
+	)
+	return err
+}
+
+// FindFeatures returns the features of schemes visible to viewer, public
+// ones and their own, whose background lightness lies in the given range.
+func (r *colorSchemeRepository) FindFeatures(ctx context.Context, viewer string, minBackgroundL, maxBackgroundL float64) ([]models.SchemeFeatures, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT f.scheme_id, f.background_l, f.slots
+		FROM color_scheme_features f
+		JOIN color_schemes s ON s.id = f.scheme_id
+		WHERE `+visibleTo(1)+` AND f.background_l BETWEEN $2 AND $3`,
+		viewer, minBackgroundL, maxBackgroundL,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []models.SchemeFeatures
+	for rows.Next() {
+		var (
+			f     models.SchemeFeatures
+			slots []byte
+		)
+		if err := rows.Scan(&f.SchemeID, &f.BackgroundL, &slots); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(slots, &f.Slots); err != nil {
+			return nil, err
+		}
+		result = append(result, f)
+	}
+	return result, rows.Err()
+}
+
+func (r *colorSchemeRepository) ListRevisions(ctx context.Context, schemeID string) ([]models.Revision, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+revisionColumns+" FROM color_scheme_revisions WHERE scheme_id = $1 ORDER BY revision DESC", schemeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var revisions []models.Revision
+	for rows.Next() {
+		var rev models.Revision
+		if err := scanRevision(rows, &rev); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, rev)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *colorSchemeRepository) GetRevision(ctx context.Context, schemeID string, revision int) (*models.Revision, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM color_scheme_revisions WHERE scheme_id = $1 AND revision = $2", schemeID, revision)
+
+	var rev models.Revision
+	if err := scanRevision(row, &rev); err != nil {
+		return nil, err
+	}
+	return &rev, nil
+}
+
+// LatestRevision returns the newest revision number of a scheme, nil for
+// schemes saved before revisions existed.
+func (r *colorSchemeRepository) LatestRevision(ctx context.Context, schemeID string) (*int, error) {
+	var revision *int
+	err := r.db.QueryRowContext(ctx, "SELECT MAX(revision) FROM color_scheme_revisions WHERE scheme_id = $1", schemeID).Scan(&revision)
+	return revision, err
+}
+
+func (r *colorSchemeRepository) SetForkedRevision(ctx context.Context, id string, revision *int) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE color_schemes SET forked_revision = $1 WHERE id = $2", revision, id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// GetAncestors walks forked_from up from id and returns the ancestors,
+// parent first.
+func (r *colorSchemeRepository) GetAncestors(ctx context.Context, id string) ([]models.LineageEntry, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`WITH RECURSIVE ancestors (id, revision, depth) AS (
+			SELECT forked_from, forked_revision, 1 FROM color_schemes WHERE id = $1 AND forked_from IS NOT NULL
+			UNION ALL
+			SELECT s.forked_from, s.forked_revision, a.depth + 1
+			FROM ancestors a JOIN color_schemes s ON s.id = a.id
+			WHERE s.forked_from IS NOT NULL AND a.depth < $2
+		)
+		SELECT s.id, s.name, s.author, s.organization, s.public, a.revision
+		FROM ancestors a JOIN color_schemes s ON s.id = a.id
+		ORDER BY a.depth`,
+		id, maxLineageDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return scanLineage(rows)
+}
+
+func (r *colorSchemeRepository) GetForks(ctx context.Context, id string) ([]models.LineageEntry, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT id, name, author, organization, public, forked_revision FROM color_schemes WHERE forked_from = $1 ORDER BY name", id)
+	if err != nil {
+		return nil, err
+	}
+	return scanLineage(rows)
+}
+
+func scanLineage(rows *sql.Rows) ([]models.LineageEntry, error) {
+	defer rows.Close()
+
+	var entries []models.LineageEntry
+	for rows.Next() {
+		var e models.LineageEntry
+		if err := rows.Scan(&e.ID, &e.Name, &e.Author, &e.Organization, &e.Public, &e.Revision); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// insertRevision records the scheme's current state under the next
+// revision number and returns that number.
+func insertRevision(ctx context.Context, tx *sql.Tx, scheme models.ColorScheme, editor string) (int, error) {
+	colors, err := json.Marshal(scheme.Colors)
+	if err != nil {
+		return 0, err
+	}
+
+	var revision int
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO color_scheme_revisions (scheme_id, revision, name, category, colors, editor)
+		SELECT $1, COALESCE(MAX(revision), 0) + 1, $2, $3, $4, $5
+		FROM color_scheme_revisions WHERE scheme_id = $1
+		RETURNING revision`,
+		scheme.ID, scheme.Name, scheme.Category, colors, editor,
+	).Scan(&revision)
+	return revision, err
+}
+
+func scanRevision(row rowScanner, rev *models.Revision) error {
+	var colors []byte
+	if err := row.Scan(&rev.SchemeID, &rev.Revision, &rev.Name, &rev.Category, &colors, &rev.Editor, &rev.CreatedAt); err != nil {
+		return err
+	}
+	return json.Unmarshal(colors, &rev.Colors)
+}
+
+func insertScheme(ctx context.Context, tx *sql.Tx, scheme models.ColorScheme) error {
+	_, err := tx.ExecContext(ctx, `INSERT INTO color_schemes (id, name, author, organization, category, public, variant_id, duplicate_of, forked_from, forked_revision)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		scheme.ID, scheme.Name, scheme.Author, scheme.Organization, scheme.Category, scheme.Public, scheme.VariantID, scheme.DuplicateOf, scheme.ForkedFrom, scheme.ForkedRevision,
+	)
+	if err != nil {
+		return err
+	}
+	for key, value := range scheme.Colors {
+		_, err := tx.ExecContext(ctx, "INSERT INTO color_scheme_colors (scheme_id, color_key, color_value) VALUES ($1, $2, $3)", scheme.ID, key, value)
+		if err != nil {
 			return err
 		}
 	}
-	return tx.Commit()
+	if err := saveMetrics(ctx, tx, scheme); err != nil {
+		return err
+	}
+	return saveTags(ctx, tx, scheme)
 }
 
-func (r *colorSchemeRepository) Delete(ctx context.Context, id string) error {
-	_, err := r.db.ExecContext(ctx, "DELETE FROM color_schemes WHERE id = $1", id)
+// saveMetrics stores the scheme's metrics, if computed, alongside the
+// scheme in the same transaction.
+func saveMetrics(ctx context.Context, tx *sql.Tx, scheme models.ColorScheme) error {
+	m := scheme.Metrics
+	if m == nil {
+		_, err := tx.ExecContext(ctx, "DELETE FROM color_scheme_metrics WHERE scheme_id = $1", scheme.ID)
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx,
+		`INSERT INTO color_scheme_metrics (scheme_id, background_luminance, foreground_contrast, average_chroma, temperature, traits)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (scheme_id) DO UPDATE SET
+			background_luminance = EXCLUDED.background_luminance,
+			foreground_contrast = EXCLUDED.foreground_contrast,
+			average_chroma = EXCLUDED.average_chroma,
+			temperature = EXCLUDED.temperature,
+			traits = EXCLUDED.traits`,
+		scheme.ID, m.BackgroundLuminance, m.ForegroundContrast, m.AverageChroma, m.Temperature, pq.Array(m.Traits),
+	)
 	return err
 }
+
+// Star records username's star on the scheme, once however often it is
+// called, and returns the scheme's star count.
+func (r *colorSchemeRepository) Star(ctx context.Context, id, username string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "INSERT INTO color_scheme_stars (scheme_id, username) VALUES ($1, $2) ON CONFLICT DO NOTHING", id, username)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return adjustStars(ctx, tx, id, res, 1)
+}
+
+// Unstar removes username's star, if any, and returns the scheme's star
+// count.
+func (r *colorSchemeRepository) Unstar(ctx context.Context, id, username string) (int, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	res, err := tx.ExecContext(ctx, "DELETE FROM color_scheme_stars WHERE scheme_id = $1 AND username = $2", id, username)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return adjustStars(ctx, tx, id, res, -1)
+}
+
+// adjustStars moves the materialized star count by delta for every row the
+// star change affected and commits tx.
+func adjustStars(ctx context.Context, tx *sql.Tx, id string, res sql.Result, delta int) (int, error) {
+	n, err := res.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx,
+		"UPDATE color_schemes SET star_count = star_count + $1 WHERE id = $2 RETURNING star_count",
+		delta*int(n), id,
+	).Scan(&count)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return count, tx.Commit()
+}
+
+// GetTrending ranks public schemes by the stars given within window, each
+// star's weight halving every halfLife.
+func (r *colorSchemeRepository) GetTrending(ctx context.Context, halfLife, window time.Duration, limit int) ([]models.TrendingColorScheme, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`WITH scores AS (
+			SELECT scheme_id, SUM(POWER(0.5, EXTRACT(EPOCH FROM NOW() - created_at) / $1)) AS score
+			FROM color_scheme_stars
+			WHERE created_at > NOW() - $2 * INTERVAL '1 second'
+			GROUP BY scheme_id
+		)
+		SELECT `+schemeColumns+`, sc.score FROM `+schemeTables+`
+		JOIN scores sc ON sc.scheme_id = s.id
+		WHERE s.public
+		ORDER BY sc.score DESC, s.star_count DESC, s.name
+		LIMIT $3`,
+		halfLife.Seconds(), window.Seconds(), limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var trending []models.TrendingColorScheme
+	for rows.Next() {
+		var t models.TrendingColorScheme
+		if err := scanScheme(rows, &t.Scheme, &t.Score); err != nil {
+			return nil, err
+		}
+		trending = append(trending, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range trending {
+		if trending[i].Scheme.Colors, err = r.loadColors(ctx, trending[i].Scheme.ID); err != nil {
+			return nil, err
+		}
+	}
+	return trending, nil
+}
+
+// saveTags replaces the scheme's tags, creating tags seen for the first
+// time. Tags must already be normalized.
+func saveTags(ctx context.Context, tx *sql.Tx, scheme models.ColorScheme) error {
+	_, err := tx.ExecContext(ctx, "DELETE FROM color_scheme_tags WHERE scheme_id = $1", scheme.ID)
+	if err != nil {
+		return err
+	}
+	for _, tag := range scheme.Tags {
+		_, err := tx.ExecContext(ctx, "INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO NOTHING", tag)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx,
+			"INSERT INTO color_scheme_tags (scheme_id, tag_id) SELECT $1, id FROM tags WHERE name = $2 ON CONFLICT DO NOTHING",
+			scheme.ID, tag,
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTags counts the schemes visible to viewer, public ones and their
+// own, carrying each tag that starts with prefix, most used first.
+func (r *colorSchemeRepository) ListTags(ctx context.Context, viewer, prefix string, limit int) ([]models.TagCount, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT t.name, COUNT(*) FROM tags t
+		JOIN color_scheme_tags st ON st.tag_id = t.id
+		JOIN color_schemes s ON s.id = st.scheme_id
+		WHERE t.name LIKE $1 || '%' AND `+visibleTo(2)+`
+		GROUP BY t.name
+		ORDER BY COUNT(*) DESC, t.name
+		LIMIT $3`,
+		prefix, viewer, limit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []models.TagCount
+	for rows.Next() {
+		var tc models.TagCount
+		if err := rows.Scan(&tc.Name, &tc.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, tc)
+	}
+	return counts, rows.Err()
+}
+
+func (r *colorSchemeRepository) loadColors(ctx context.Context, schemeID string) (map[string]string, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT color_key, color_value FROM color_scheme_colors WHERE scheme_id = $1", schemeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	colors := make(map[string]string)
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, err
+		}
+		colors[key] = value
+	}
+	return colors, rows.Err()
+}
+
+// scanScheme reads schemeColumns into s, followed by any extra columns
+// the query selects.
+func scanScheme(row rowScanner, s *models.ColorScheme, extra ...any) error {
+	var (
+		luminance, contrast, chroma, temperature sql.NullFloat64
+		traits                                   []string
+	)
+	dest := []any{&s.ID, &s.Name, &s.Author, &s.Organization, &s.Category, &s.Public, &s.Version, &s.Stars, &s.VariantID, &s.DuplicateOf, &s.ForkedFrom, &s.ForkedRevision, &s.AccessibilityScore,
+		&luminance, &contrast, &chroma, &temperature, pq.Array(&traits), pq.Array(&s.Tags)}
+	if err := row.Scan(append(dest, extra...)...); err != nil {
+		return err
+	}
+
+	if luminance.Valid {
+		s.Metrics = &models.SchemeMetrics{
+			BackgroundLuminance: luminance.Float64,
+			ForegroundContrast:  contrast.Float64,
+			AverageChroma:       chroma.Float64,
+			Temperature:         temperature.Float64,
+			Traits:              traits,
+		}
+	}
+	return nil
+}
+
+// visibleTo is the condition under which the viewer bound to placeholder
+// param may see scheme s: it is public, a personal scheme of theirs or owned
+// by an organization they belong to.
+func visibleTo(param int) string {
+	return fmt.Sprintf("(s.public OR (s.organization IS NULL AND s.author = $%[1]d) OR "+
+		"s.organization IN (SELECT organization FROM organization_members WHERE username = $%[1]d))", param)
+}
+
+// applyFilter appends the filter's conditions to a query whose WHERE clause
+// already uses len(args) placeholders.
+func applyFilter(query string, args []any, filter models.ColorSchemeFilter) (string, []any) {
+	if filter.MinAccessibility != nil {
+		args = append(args, *filter.MinAccessibility)
+		query += fmt.Sprintf(" AND a.score >= $%d", len(args))
+	}
+	if filter.Category != "" {
+		args = append(args, filter.Category)
+		query += fmt.Sprintf(" AND LOWER(s.category) = LOWER($%d)", len(args))
+	}
+	if len(filter.Traits) > 0 {
+		args = append(args, pq.Array(filter.Traits))
+		query += fmt.Sprintf(" AND m.traits @> $%d", len(args))
+	}
+	if filter.MinChroma != nil {
+		args = append(args, *filter.MinChroma)
+		query += fmt.Sprintf(" AND m.average_chroma >= $%d", len(args))
+	}
+	if filter.MaxChroma != nil {
+		args = append(args, *filter.MaxChroma)
+		query += fmt.Sprintf(" AND m.average_chroma <= $%d", len(args))
+	}
+	if filter.MinTemperature != nil {
+		args = append(args, *filter.MinTemperature)
+		query += fmt.Sprintf(" AND m.temperature >= $%d", len(args))
+	}
+	if filter.MaxTemperature != nil {
+		args = append(args, *filter.MaxTemperature)
+		query += fmt.Sprintf(" AND m.temperature <= $%d", len(args))
+	}
+	if filter.MinContrast != nil {
+		args = append(args, *filter.MinContrast)
+		query += fmt.Sprintf(" AND m.foreground_contrast >= $%d", len(args))
+	}
+	if len(filter.Tags) > 0 {
+		args = append(args, pq.Array(filter.Tags), len(filter.Tags))
+		query += fmt.Sprintf(` AND s.id IN (SELECT st.scheme_id FROM color_scheme_tags st JOIN tags t ON t.id = st.tag_id
+			WHERE t.name = ANY($%d) GROUP BY st.scheme_id HAVING COUNT(*) = $%d)`, len(args)-1, len(args))
+	}
+	query += " ORDER BY s.name"
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	return query, args
+}
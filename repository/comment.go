@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+type CommentRepository interface {
+	List(ctx context.Context, schemeID string) ([]models.Comment, error)
+	GetById(ctx context.Context, id string) (*models.Comment, error)
+	Create(ctx context.Context, comment models.Comment) error
+	Resolve(ctx context.Context, id string, status models.SuggestionStatus, resolvedBy string, revision *int) error
+	Delete(ctx context.Context, id string) error
+}
+
+const commentColumns = "id, scheme_id, parent_id, author, body, kind, changes, status, resolved_by, resolved_at, revision, deleted, created_at"
+
+type commentRepository struct {
+	db *sql.DB
+}
+
+func NewCommentRepository(db *sql.DB) CommentRepository {
+	return &commentRepository{db: db}
+}
+
+// List returns every comment on the scheme, oldest first.
+func (r *commentRepository) List(ctx context.Context, schemeID string) ([]models.Comment, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+commentColumns+" FROM color_scheme_comments WHERE scheme_id = $1 ORDER BY created_at, id", schemeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var comments []models.Comment
+	for rows.Next() {
+		var c models.Comment
+		if err := scanComment(rows, &c); err != nil {
+			return nil, err
+		}
+		comments = append(comments, c)
+	}
+	return comments, rows.Err()
+}
+
+func (r *commentRepository) GetById(ctx context.Context, id string) (*models.Comment, error) {
+	var c models.Comment
+	row := r.db.QueryRowContext(ctx, "SELECT "+commentColumns+" FROM color_scheme_comments WHERE id = $1", id)
+	if err := scanComment(row, &c); err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *commentRepository) Create(ctx context.Context, comment models.Comment) error {
+	var changes *string
+	if comment.Kind == models.CommentKindSuggestion {
+		data, err := json.Marshal(comment.Changes)
+		if err != nil {
+			return err
+		}
+		encoded := string(data)
+		changes = &encoded
+	}
+
+	var status *models.SuggestionStatus
+	if comment.Status != "" {
+		status = &comment.Status
+	}
+
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO color_scheme_comments (id, scheme_id, parent_id, author, body, kind, changes, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		comment.ID, comment.SchemeID, comment.ParentID, comment.Author, comment.Body, comment.Kind, changes, status,
+	)
+	return err
+}
+
+// Resolve accepts or rejects an open suggestion. A suggestion that is no
+// longer open returns sql.ErrNoRows.
+func (r *commentRepository) Resolve(ctx context.Context, id string, status models.SuggestionStatus, resolvedBy string, revision *int) error {
+	res, err := r.db.ExecContext(ctx,
+		`UPDATE color_scheme_comments SET status = $1, resolved_by = $2, resolved_at = CURRENT_TIMESTAMP, revision = $3
+		WHERE id = $4 AND status = $5 AND NOT deleted`,
+		status, resolvedBy, revision, id, models.SuggestionOpen,
+	)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// Delete clears the comment's body and changes but keeps it in place so its
+// replies stay threaded.
+func (r *commentRepository) Delete(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE color_scheme_comments SET body = '', changes = NULL, deleted = TRUE WHERE id = $1 AND NOT deleted", id)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func scanComment(row rowScanner, c *models.Comment) error {
+	var (
+		changes []byte
+		status  sql.NullString
+	)
+	err := row.Scan(&c.ID, &c.SchemeID, &c.ParentID, &c.Author, &c.Body, &c.Kind, &changes, &status,
+		&c.ResolvedBy, &c.ResolvedAt, &c.Revision, &c.Deleted, &c.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	c.Status = models.SuggestionStatus(status.String)
+	if changes != nil {
+		return json.Unmarshal(changes, &c.Changes)
+	}
+	return nil
+}
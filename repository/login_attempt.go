@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+type LoginAttemptRepository interface {
+	GetThrottle(ctx context.Context, scope models.ThrottleScope, key string) (*models.LoginThrottle, error)
+	RecordAttempt(ctx context.Context, scope models.ThrottleScope, key string, limits AttemptLimits) (bool, error)
+	Release(ctx context.Context, scope models.ThrottleScope, key string) error
+	Reset(ctx context.Context, scope models.ThrottleScope, key string) error
+	Audit(ctx context.Context, audit models.LoginAudit) error
+}
+
+// AttemptLimits configures RecordAttempt. Once key is past Free attempts,
+// every further attempt locks it out for BaseDelay doubled per attempt,
+// capped at MaxLockout. Counters whose last attempt is older than
+// ResetBefore start over.
+type AttemptLimits struct {
+	Free        int
+	BaseDelay   time.Duration
+	MaxLockout  time.Duration
+	ResetBefore time.Time
+}
+
+type loginAttemptRepository struct {
+	db *sql.DB
+}
+
+func NewLoginAttemptRepository(db *sql.DB) LoginAttemptRepository {
+	return &loginAttemptRepository{db: db}
+}
+
+// GetThrottle returns nil when key has no recorded failures.
+func (r *loginAttemptRepository) GetThrottle(ctx context.Context, scope models.ThrottleScope, key string) (*models.LoginThrottle, error) {
+	var t models.LoginThrottle
+	err := r.db.QueryRowContext(ctx, "SELECT scope, key, failures, last_failure_at, locked_until FROM login_throttles WHERE scope = $1 AND key = $2", scope, key).
+		Scan(&t.Scope, &t.Key, &t.Failures, &t.LastFailureAt, &t.LockedUntil)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecordAttempt counts an attempt for key and reports whether it may go
+// ahead. It returns false without counting anything while key is locked
+// out. The lockout test and the increment are a single UPDATE, so
+// concurrent attempts are serialized on the row and can't all get in under
+// the limit.
+func (r *loginAttemptRepository) RecordAttempt(ctx context.Context, scope models.ThrottleScope, key string, limits AttemptLimits) (bool, error) {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO login_throttles (scope, key, failures, last_failure_at) VALUES ($1, $2, 0, CURRENT_TIMESTAMP)
+		ON CONFLICT (scope, key) DO UPDATE SET failures = 0, locked_until = NULL
+		WHERE login_throttles.last_failure_at < $3 AND (login_throttles.locked_until IS NULL OR login_throttles.locked_until <= CURRENT_TIMESTAMP)`,
+		scope, key, limits.ResetBefore,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	// failures still holds the old count on the right-hand side, so the
+	// attempt being counted is failures + 1 and past the free ones by
+	// failures + 1 - $3
+	var failures int
+	err = r.db.QueryRowContext(ctx,
+		`UPDATE login_throttles SET
+			failures = failures + 1,
+			last_failure_at = CURRENT_TIMESTAMP,
+			locked_until = CASE WHEN failures + 1 > $3
+				THEN CURRENT_TIMESTAMP + LEAST($4 * power(2, failures - $3), $5) * INTERVAL '1 second'
+			END
+		WHERE scope = $1 AND key = $2 AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+		RETURNING failures`,
+		scope, key, limits.Free, limits.BaseDelay.Seconds(), limits.MaxLockout.Seconds(),
+	).Scan(&failures)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Release gives back an attempt counted by RecordAttempt that turned out
+// not to be a failure. A lockout the attempt started is left in place.
+func (r *loginAttemptRepository) Release(ctx context.Context, scope models.ThrottleScope, key string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE login_throttles SET failures = GREATEST(failures - 1, 0) WHERE scope = $1 AND key = $2", scope, key)
+	return err
+}
+
+func (r *loginAttemptRepository) Reset(ctx context.Context, scope models.ThrottleScope, key string) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM login_throttles WHERE scope = $1 AND key = $2", scope, key)
+	return err
+}
+
+func (r *loginAttemptRepository) Audit(ctx context.Context, audit models.LoginAudit) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO login_audit (username, ip, success, reason) VALUES ($1, $2, $3, $4)",
+		audit.Username, audit.IP, audit.Success, audit.Reason)
+	return err
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/nqvinh00/colorscheme/models"
+)
+
+type OrganizationRepository interface {
+	Create(ctx context.Context, org models.Organization, owner string) error
+	GetByName(ctx context.Context, name string) (*models.Organization, error)
+	ListByMember(ctx context.Context, username string) ([]models.Organization, error)
+	Delete(ctx context.Context, name string) error
+	GetMembers(ctx context.Context, name string) ([]models.OrgMember, error)
+	GetRoles(ctx context.Context, username string) (map[string]models.OrgRole, error)
+	SetMember(ctx context.Context, name, username string, role models.OrgRole) error
+	RemoveMember(ctx context.Context, name, username string) error
+}
+
+type organizationRepository struct {
+	db *sql.DB
+}
+
+func NewOrganizationRepository(db *sql.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create stores the organization with owner as its first member.
+func (r *organizationRepository) Create(ctx context.Context, org models.Organization, owner string) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO organizations (name, display_name) VALUES ($1, $2)", org.Name, org.DisplayName)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "INSERT INTO organization_members (organization, username, role) VALUES ($1, $2, $3)", org.Name, owner, models.OrgRoleOwner)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *organizationRepository) GetByName(ctx context.Context, name string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.QueryRowContext(ctx, "SELECT name, display_name, created_at FROM organizations WHERE name = $1", name).
+		Scan(&org.Name, &org.DisplayName, &org.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ListByMember lists the organizations username belongs to with their role
+// in each.
+func (r *organizationRepository) ListByMember(ctx context.Context, username string) ([]models.Organization, error) {
+	rows, err := r.db.QueryContext(ctx,
+		`SELECT o.name, o.display_name, o.created_at, m.role
+		FROM organizations o JOIN organization_members m ON m.organization = o.name
+		WHERE m.username = $1
+		ORDER BY o.name`,
+		username,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var orgs []models.Organization
+	for rows.Next() {
+		var org models.Organization
+		if err := rows.Scan(&org.Name, &org.DisplayName, &org.CreatedAt, &org.Role); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+// Delete removes the organization and its memberships. Its schemes go back
+// to their authors.
+func (r *organizationRepository) Delete(ctx context.Context, name string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM organizations WHERE name = $1", name)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *organizationRepository) GetMembers(ctx context.Context, name string) ([]models.OrgMember, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT username, role, joined_at FROM organization_members WHERE organization = $1 ORDER BY username", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var members []models.OrgMember
+	for rows.Next() {
+		var m models.OrgMember
+		if err := rows.Scan(&m.Username, &m.Role, &m.JoinedAt); err != nil {
+			return nil, err
+		}
+		members = append(members, m)
+	}
+	return members, rows.Err()
+}
+
+// GetRoles returns username's role in every organization they belong to.
+func (r *organizationRepository) GetRoles(ctx context.Context, username string) (map[string]models.OrgRole, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT organization, role FROM organization_members WHERE username = $1", username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	roles := make(map[string]models.OrgRole)
+	for rows.Next() {
+		var (
+			org  string
+			role models.OrgRole
+		)
+		if err := rows.Scan(&org, &role); err != nil {
+			return nil, err
+		}
+		roles[org] = role
+	}
+	return roles, rows.Err()
+}
+
+// SetMember adds username to the organization or changes their role.
+func (r *organizationRepository) SetMember(ctx context.Context, name, username string, role models.OrgRole) error {
+	_, err := r.db.ExecContext(ctx,
+		`INSERT INTO organization_members (organization, username, role) VALUES ($1, $2, $3)
+		ON CONFLICT (organization, username) DO UPDATE SET role = EXCLUDED.role`,
+		name, username, role,
+	)
+	return err
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, name, username string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM organization_members WHERE organization = $1 AND username = $2", name, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
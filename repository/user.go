@@ -3,13 +3,36 @@ package repository
 import (
 	"context"
 	"database/sql"
+
+	"github.com/nqvinh00/colorscheme/models"
 )
 
 type UserRepository interface {
-	CreateAccount(ctx context.Context, username, password string) error
+	CreateAccount(ctx context.Context, username, password string, email *string) error
 	Login(ctx context.Context, username string, hashed *string) error
+	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	UpdateEmail(ctx context.Context, username, email string) error
+	MarkEmailVerified(ctx context.Context, username, email string) error
+	UpdatePassword(ctx context.Context, username, hashed string) error
+	CreateToken(ctx context.Context, token models.UserToken) error
+	GetToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	ConsumeToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error)
+	DeleteTokens(ctx context.Context, username string, purpose models.TokenPurpose) error
+	SetTOTPSecret(ctx context.Context, username, secret string) error
+	EnableTOTP(ctx context.Context, username string, counter int64, recoveryCodeHashes []string) error
+	DisableTOTP(ctx context.Context, username string) error
+	UseTOTPCounter(ctx context.Context, username string, counter int64) error
+	ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error
+	ConsumeRecoveryCode(ctx context.Context, username, codeHash string) error
+	List(ctx context.Context) ([]models.User, error)
+	SetRole(ctx context.Context, username string, role models.Role) error
+	SetDisabled(ctx context.Context, username string, disabled bool) error
+	UsernameTaken(ctx context.Context, username string) (bool, error)
 }
 
+const userColumns = "username, password, email, email_verified, totp_secret, totp_enabled, role, disabled, created_at, updated_at"
+
 type userRepository struct {
 	db *sql.DB
 }
@@ -18,11 +41,236 @@ func NewUserRepository(db *sql.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
-func (r *userRepository) CreateAccount(ctx context.Context, username, password string) error {
-	_, err := r.db.ExecContext(ctx, "INSERT INTO users (username, password) VALUES ($1, $2)", username, password)
+func (r *userRepository) CreateAccount(ctx context.Context, username, password string, email *string) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO users (username, password, email) VALUES ($1, $2, $3)", username, password, email)
 	return err
 }
 
 func (r *userRepository) Login(ctx context.Context, username string, hashed *string) error {
 	return r.db.QueryRowContext(ctx, "SELECT password FROM users WHERE username = $1", username).Scan(hashed)
 }
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE username = $1", username)
+	return scanUser(row)
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+userColumns+" FROM users WHERE email = $1", email)
+	return scanUser(row)
+}
+
+func (r *userRepository) UpdateEmail(ctx context.Context, username, email string) error {
+	_, err := r.db.ExecContext(ctx, "UPDATE users SET email = $1, email_verified = FALSE, updated_at = CURRENT_TIMESTAMP WHERE username = $2", email, username)
+	return err
+}
+
+func (r *userRepository) MarkEmailVerified(ctx context.Context, username, email string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET email_verified = TRUE, updated_at = CURRENT_TIMESTAMP WHERE username = $1 AND email = $2", username, email)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, username, hashed string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET password = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2", hashed, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *userRepository) CreateToken(ctx context.Context, token models.UserToken) error {
+	_, err := r.db.ExecContext(ctx, "INSERT INTO user_tokens (token_hash, username, purpose, email, expires_at) VALUES ($1, $2, $3, $4, $5)",
+		token.TokenHash, token.Username, token.Purpose, token.Email, token.ExpiresAt)
+	return err
+}
+
+// ConsumeToken marks an unused, unexpired token as used and returns it. The
+// update is a single statement so a token can never be redeemed twice.
+// GetToken returns an unused, unexpired token without redeeming it.
+func (r *userRepository) GetToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.QueryRowContext(ctx,
+		`SELECT token_hash, username, purpose, email, expires_at FROM user_tokens
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP`,
+		tokenHash, purpose,
+	).Scan(&token.TokenHash, &token.Username, &token.Purpose, &token.Email, &token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *userRepository) ConsumeToken(ctx context.Context, tokenHash string, purpose models.TokenPurpose) (*models.UserToken, error) {
+	var token models.UserToken
+	err := r.db.QueryRowContext(ctx,
+		`UPDATE user_tokens SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING token_hash, username, purpose, email, expires_at`,
+		tokenHash, purpose,
+	).Scan(&token.TokenHash, &token.Username, &token.Purpose, &token.Email, &token.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *userRepository) DeleteTokens(ctx context.Context, username string, purpose models.TokenPurpose) error {
+	_, err := r.db.ExecContext(ctx, "DELETE FROM user_tokens WHERE username = $1 AND purpose = $2", username, purpose)
+	return err
+}
+
+// SetTOTPSecret stores a pending secret. It is not used for login until
+// EnableTOTP confirms the user can produce codes for it.
+func (r *userRepository) SetTOTPSecret(ctx context.Context, username, secret string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET totp_secret = $1, totp_enabled = FALSE, totp_last_counter = 0, updated_at = CURRENT_TIMESTAMP WHERE username = $2 AND totp_enabled = FALSE", secret, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *userRepository) EnableTOTP(ctx context.Context, username string, counter int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_enabled = TRUE, totp_last_counter = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2", counter, username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, username, recoveryCodeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *userRepository) DisableTOTP(ctx context.Context, username string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE users SET totp_secret = NULL, totp_enabled = FALSE, totp_last_counter = 0, updated_at = CURRENT_TIMESTAMP WHERE username = $1", username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	_, err = tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE username = $1", username)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// UseTOTPCounter records the time step of an accepted code. It fails with
+// sql.ErrNoRows if that step or a later one was already used, so a code
+// cannot be replayed.
+func (r *userRepository) UseTOTPCounter(ctx context.Context, username string, counter int64) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET totp_last_counter = $1 WHERE username = $2 AND totp_last_counter < $1", counter, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *userRepository) ReplaceRecoveryCodes(ctx context.Context, username string, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := replaceRecoveryCodes(ctx, tx, username, codeHashes); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *userRepository) ConsumeRecoveryCode(ctx context.Context, username, codeHash string) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP WHERE username = $1 AND code_hash = $2 AND used_at IS NULL", username, codeHash)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, username string, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, "DELETE FROM user_recovery_codes WHERE username = $1", username); err != nil {
+		return err
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, "INSERT INTO user_recovery_codes (username, code_hash) VALUES ($1, $2)", username, hash); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *userRepository) List(ctx context.Context) ([]models.User, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+userColumns+" FROM users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+	return users, rows.Err()
+}
+
+func (r *userRepository) SetRole(ctx context.Context, username string, role models.Role) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET role = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2", role, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+func (r *userRepository) SetDisabled(ctx context.Context, username string, disabled bool) error {
+	res, err := r.db.ExecContext(ctx, "UPDATE users SET disabled = $1, updated_at = CURRENT_TIMESTAMP WHERE username = $2", disabled, username)
+	if err != nil {
+		return err
+	}
+	return expectAffected(res)
+}
+
+// UsernameTaken compares case-insensitively so "Alice" and "alice" cannot
+// both register.
+func (r *userRepository) UsernameTaken(ctx context.Context, username string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE LOWER(username) = LOWER($1))", username).Scan(&exists)
+	return exists, err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	if err := row.Scan(&user.Username, &user.Password, &user.Email, &user.EmailVerified, &user.TOTPSecret, &user.TOTPEnabled, &user.Role, &user.Disabled, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+func expectAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/repository"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrInvalidRole = errors.New("invalid role")
+	ErrSelfAction  = errors.New("cannot apply this action to your own account")
+)
+
+// AdminService holds moderation operations that bypass per-user ownership
+// checks. Callers are expected to have passed middleware.RequireRole.
+type AdminService interface {
+	ListUsers(ctx context.Context) ([]models.User, error)
+	SetUserDisabled(ctx context.Context, actor string, actorRole models.Role, username string, disabled bool) error
+	SetUserRole(ctx context.Context, actor, username string, role models.Role) error
+	ReassignColorScheme(ctx context.Context, actor, id, author string) error
+	DeleteColorScheme(ctx context.Context, actor, id string) error
+}
+
+type adminService struct {
+	userRepo        repository.UserRepository
+	colorSchemeRepo repository.ColorSchemeRepository
+	log             zerolog.Logger
+}
+
+func NewAdminService(userRepo repository.UserRepository, colorSchemeRepo repository.ColorSchemeRepository, log zerolog.Logger) AdminService {
+	return &adminService{
+		userRepo:        userRepo,
+		colorSchemeRepo: colorSchemeRepo,
+		log:             log,
+	}
+}
+
+func (s *adminService) ListUsers(ctx context.Context) ([]models.User, error) {
+	users, err := s.userRepo.List(ctx)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to list users")
+		return nil, err
+	}
+
+	if len(users) == 0 {
+		users = []models.User{}
+	}
+
+	return users, nil
+}
+
+// SetUserDisabled disables or re-enables an account. Moderators may only act
+// on regular users.
+func (s *adminService) SetUserDisabled(ctx context.Context, actor string, actorRole models.Role, username string, disabled bool) error {
+	if actor == username {
+		return ErrSelfAction
+	}
+
+	target, err := s.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if actorRole != models.RoleAdmin && target.Role != models.RoleUser {
+		return ErrForbidden
+	}
+
+	if err := s.userRepo.SetDisabled(ctx, username, disabled); err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to set account status")
+		return err
+	}
+
+	s.log.Info().Str("actor", actor).Str("username", username).Bool("disabled", disabled).Msg("Account status changed")
+	return nil
+}
+
+func (s *adminService) SetUserRole(ctx context.Context, actor, username string, role models.Role) error {
+	if !role.Valid() {
+		return ErrInvalidRole
+	}
+
+	// Keeps at least the acting admin around
+	if actor == username {
+		return ErrSelfAction
+	}
+
+	if _, err := s.getUser(ctx, username); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.SetRole(ctx, username, role); err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to set role")
+		return err
+	}
+
+	s.log.Info().Str("actor", actor).Str("username", username).Str("role", string(role)).Msg("Role changed")
+	return nil
+}
+
+func (s *adminService) ReassignColorScheme(ctx context.Context, actor, id, author string) error {
+	if _, err := s.getUser(ctx, author); err != nil {
+		return err
+	}
+
+	if err := s.colorSchemeRepo.UpdateAuthor(ctx, id, author); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrColorSchemeNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to reassign color scheme")
+		return err
+	}
+
+	s.log.Info().Str("actor", actor).Str("id", id).Str("author", author).Msg("Color scheme reassigned")
+	return nil
+}
+
+func (s *adminService) DeleteColorScheme(ctx context.Context, actor, id string) error {
+	if _, err := s.colorSchemeRepo.GetById(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrColorSchemeNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get color scheme")
+		return err
+	}
+
+	if err := s.colorSchemeRepo.Delete(ctx, id, models.AnyVersion); err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to delete color scheme")
+		return err
+	}
+
+	s.log.Info().Str("actor", actor).Str("id", id).Msg("Color scheme deleted by moderator")
+	return nil
+}
+
+func (s *adminService) getUser(ctx context.Context, username string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotFound
+		}
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get user")
+		return nil, err
+	}
+
+	return user, nil
+}
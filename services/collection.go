@@ -0,0 +1,341 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/palette"
+	"github.com/nqvinh00/colorscheme/pkg/utils"
+	"github.com/nqvinh00/colorscheme/repository"
+	"github.com/rs/zerolog"
+)
+
+var ErrCollectionNotFound = errors.New("collection not found")
+
+// Limits on what a collection may hold.
+const (
+	maxCollectionName        = 100
+	maxCollectionDescription = 1000
+	maxCollectionSchemes     = 100
+)
+
+type CollectionService interface {
+	ListCollections(ctx context.Context, username string) ([]models.Collection, error)
+	GetCollection(ctx context.Context, username, id string) (*models.Collection, error)
+	CreateCollection(ctx context.Context, username string, req models.CollectionRequest) (*models.Collection, error)
+	UpdateCollection(ctx context.Context, username, id string, req models.CollectionRequest) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, username, id string) error
+	ExportCollection(ctx context.Context, username, id, format string) (*models.ExportFile, error)
+}
+
+type collectionService struct {
+	collectionRepo  repository.CollectionRepository
+	colorSchemeRepo repository.ColorSchemeRepository
+	orgRepo         repository.OrganizationRepository
+	log             zerolog.Logger
+}
+
+func NewCollectionService(collectionRepo repository.CollectionRepository, colorSchemeRepo repository.ColorSchemeRepository, orgRepo repository.OrganizationRepository, log zerolog.Logger) CollectionService {
+	return &collectionService{
+		collectionRepo:  collectionRepo,
+		colorSchemeRepo: colorSchemeRepo,
+		orgRepo:         orgRepo,
+		log:             log,
+	}
+}
+
+func (s *collectionService) ListCollections(ctx context.Context, username string) ([]models.Collection, error) {
+	collections, err := s.collectionRepo.GetByOwner(ctx, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("owner", username).Msg("Failed to list collections")
+		return nil, err
+	}
+
+	if len(collections) == 0 {
+		collections = []models.Collection{}
+	}
+
+	return collections, nil
+}
+
+// GetCollection returns a public collection or one of username's own with
+// the schemes they can see. Schemes made private since they were added are
+// left out, along with their IDs.
+func (s *collectionService) GetCollection(ctx context.Context, username, id string) (*models.Collection, error) {
+	collection, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes, err := s.loadSchemes(ctx, username, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	collection.SchemeIDs = make([]string, len(schemes))
+	collection.Schemes = make([]models.ColorScheme, len(schemes))
+	for i, scheme := range schemes {
+		collection.SchemeIDs[i] = scheme.ID
+		collection.Schemes[i] = scheme
+	}
+
+	return collection, nil
+}
+
+func (s *collectionService) CreateCollection(ctx context.Context, username string, req models.CollectionRequest) (*models.Collection, error) {
+	collection, err := s.validate(ctx, username, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.ID, err = utils.GenerateID(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to generate collection id")
+		return nil, err
+	}
+	collection.Owner = username
+
+	if err := s.collectionRepo.Create(ctx, *collection); err != nil {
+		s.log.Error().Err(err).Msg("Failed to create collection")
+		return nil, err
+	}
+
+	return s.collectionRepo.GetById(ctx, collection.ID)
+}
+
+// UpdateCollection replaces the name, description, visibility and schemes
+// of one of username's collections.
+func (s *collectionService) UpdateCollection(ctx context.Context, username, id string, req models.CollectionRequest) (*models.Collection, error) {
+	if _, err := s.authorize(ctx, username, id); err != nil {
+		return nil, err
+	}
+
+	collection, err := s.validate(ctx, username, req)
+	if err != nil {
+		return nil, err
+	}
+	collection.ID = id
+	collection.Owner = username
+
+	if err := s.collectionRepo.Update(ctx, *collection); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to update collection")
+		return nil, err
+	}
+
+	return s.collectionRepo.GetById(ctx, id)
+}
+
+func (s *collectionService) DeleteCollection(ctx context.Context, username, id string) error {
+	if _, err := s.authorize(ctx, username, id); err != nil {
+		return err
+	}
+
+	if err := s.collectionRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCollectionNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to delete collection")
+		return err
+	}
+
+	return nil
+}
+
+// ExportCollection writes every scheme of the collection username can see
+// in format and bundles the files into a zip archive, in collection order.
+func (s *collectionService) ExportCollection(ctx context.Context, username, id, format string) (*models.ExportFile, error) {
+	f, err := lookupExportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	schemes, err := s.loadSchemes(ctx, username, collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	names := make(map[string]bool, len(schemes))
+	for _, scheme := range schemes {
+		data, err := f.Export(scheme)
+		if err != nil {
+			return nil, &ValidationError{Fields: []models.FieldError{{
+				Field:   "scheme_ids",
+				Code:    "invalid",
+				Message: fmt.Sprintf("color scheme %s: %v", scheme.ID, err),
+			}}}
+		}
+
+		// Schemes sharing a name get numbered files
+		base := palette.Slug(scheme.Name, scheme.ID)
+		name := base + f.Extension
+		for i := 2; names[name]; i++ {
+			name = fmt.Sprintf("%s-%d%s", base, i, f.Extension)
+		}
+		names[name] = true
+
+		w, err := archive.Create(name)
+		if err == nil {
+			_, err = w.Write(data)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return nil, err
+	}
+
+	return &models.ExportFile{
+		Name:        palette.Slug(collection.Name, collection.ID) + "-" + f.Name + ".zip",
+		ContentType: "application/zip",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// validate checks a collection request and returns the collection it
+// describes. Every scheme must be visible to username; repeated IDs keep
+// their first position.
+func (s *collectionService) validate(ctx context.Context, username string, req models.CollectionRequest) (*models.Collection, error) {
+	var fields []models.FieldError
+
+	name := strings.TrimSpace(req.Name)
+	switch {
+	case name == "":
+		fields = append(fields, models.FieldError{Field: "name", Code: "required", Message: "name is required"})
+	case len(name) > maxCollectionName:
+		fields = append(fields, models.FieldError{Field: "name", Code: "too_long", Message: fmt.Sprintf("name must be at most %d characters", maxCollectionName)})
+	}
+	if len(req.Description) > maxCollectionDescription {
+		fields = append(fields, models.FieldError{Field: "description", Code: "too_long", Message: fmt.Sprintf("description must be at most %d characters", maxCollectionDescription)})
+	}
+
+	seen := make(map[string]bool, len(req.SchemeIDs))
+	schemeIDs := []string{}
+	for _, schemeID := range req.SchemeIDs {
+		if seen[schemeID] {
+			continue
+		}
+		seen[schemeID] = true
+		schemeIDs = append(schemeIDs, schemeID)
+	}
+	if len(schemeIDs) > maxCollectionSchemes {
+		fields = append(fields, models.FieldError{Field: "scheme_ids", Code: "too_many", Message: fmt.Sprintf("a collection holds at most %d schemes", maxCollectionSchemes)})
+		schemeIDs = nil
+	}
+
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	for _, schemeID := range schemeIDs {
+		scheme, err := s.colorSchemeRepo.GetById(ctx, schemeID)
+		if errors.Is(err, sql.ErrNoRows) || (err == nil && !a.canView(scheme.Public, scheme.Author, scheme.Organization)) {
+			fields = append(fields, models.FieldError{Field: "scheme_ids", Code: "not_found", Message: "color scheme " + schemeID + " not found"})
+			continue
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("id", schemeID).Msg("Failed to get color scheme")
+			return nil, err
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	return &models.Collection{
+		Name:        name,
+		Description: req.Description,
+		Public:      req.Public,
+		SchemeIDs:   schemeIDs,
+	}, nil
+}
+
+// loadSchemes loads the collection's schemes that username can see, in
+// collection order.
+func (s *collectionService) loadSchemes(ctx context.Context, username string, collection *models.Collection) ([]models.ColorScheme, error) {
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	var schemes []models.ColorScheme
+	for _, schemeID := range collection.SchemeIDs {
+		scheme, err := s.colorSchemeRepo.GetById(ctx, schemeID)
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("id", schemeID).Msg("Failed to get color scheme")
+			return nil, err
+		}
+		if a.canView(scheme.Public, scheme.Author, scheme.Organization) {
+			schemes = append(schemes, *scheme)
+		}
+	}
+	return schemes, nil
+}
+
+func (s *collectionService) access(ctx context.Context, username string) (access, error) {
+	a, err := loadAccess(ctx, s.orgRepo, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get organization roles")
+	}
+	return a, err
+}
+
+// getVisible loads a collection username may see, a public one or their
+// own. Hidden collections are reported as not found.
+func (s *collectionService) getVisible(ctx context.Context, username, id string) (*models.Collection, error) {
+	collection, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !collection.Public && collection.Owner != username {
+		return nil, ErrCollectionNotFound
+	}
+
+	return collection, nil
+}
+
+// authorize loads the collection and checks that username owns it.
+func (s *collectionService) authorize(ctx context.Context, username, id string) (*models.Collection, error) {
+	collection, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if collection.Owner != username {
+		return nil, ErrForbidden
+	}
+
+	return collection, nil
+}
+
+func (s *collectionService) get(ctx context.Context, id string) (*models.Collection, error) {
+	collection, err := s.collectionRepo.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCollectionNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get collection")
+		return nil, err
+	}
+	return collection, nil
+}
@@ -1,36 +1,120 @@
 package services
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"maps"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+	"github.com/nqvinh00/colorscheme/pkg/jsonpatch"
+	"github.com/nqvinh00/colorscheme/pkg/palette"
+	"github.com/nqvinh00/colorscheme/pkg/tags"
+	"github.com/nqvinh00/colorscheme/pkg/utils"
 	"github.com/nqvinh00/colorscheme/repository"
 	"github.com/rs/zerolog"
 )
 
+var (
+	ErrColorSchemeNotFound = errors.New("color scheme not found")
+	ErrForbidden           = errors.New("forbidden")
+	ErrVariantExists       = errors.New("color scheme already has a variant")
+	ErrRevisionNotFound    = errors.New("revision not found")
+)
+
+// VersionConflictError is returned when an update or delete was based on a
+// version of the scheme that has since changed.
+type VersionConflictError struct {
+	Current int
+}
+
+func (e *VersionConflictError) Error() string {
+	return fmt.Sprintf("color scheme has changed, current version is %d", e.Current)
+}
+
+// Uploaded images larger than this are rejected before decoding so a small
+// file can't expand into a huge bitmap.
+const maxImagePixels = 40_000_000
+
+// Similarity search only compares schemes whose background CIELAB lightness
+// is within this distance of the source's.
+const similarityWindow = 15.0
+
+// Trending counts the stars of the last trendingWindow, each weighing half
+// as much per trendingHalfLife since it was given.
+const (
+	trendingHalfLife = 3 * 24 * time.Hour
+	trendingWindow   = 30 * 24 * time.Hour
+)
+
+// Bounds on the number of clusters a client may ask extraction for.
+const (
+	minExtractClusters = 8
+	maxExtractClusters = 32
+)
+
 type ColorSchemeService interface {
-	GetAllColorSchemesByAuthor(ctx context.Context, author string) ([]models.ColorScheme, error)
-	GetColorSchemeById(ctx context.Context, id string) (*models.ColorScheme, error)
-	CreateColorScheme(ctx context.Context, colorScheme models.ColorScheme) error
-	UpdateColorScheme(ctx context.Context, colorScheme models.ColorScheme) error
-	DeleteColorScheme(ctx context.Context, id string) error
+	GetAllColorSchemesByAuthor(ctx context.Context, author string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	GetColorSchemeById(ctx context.Context, username, id string) (*models.ColorScheme, error)
+	CreateColorScheme(ctx context.Context, username string, colorScheme models.ColorScheme) (*models.ColorScheme, error)
+	UpdateColorScheme(ctx context.Context, username string, colorScheme models.ColorScheme) (*models.ColorScheme, error)
+	PatchColorScheme(ctx context.Context, username, id string, version int, contentType string, patch []byte) (*models.ColorScheme, error)
+	DeleteColorScheme(ctx context.Context, username, id string, version int) error
+	GetAccessibilityReport(ctx context.Context, username, id string) (*models.AccessibilityReport, error)
+	SimulateColorVision(ctx context.Context, username, id, deficiency string, severity float64) ([]models.CVDSimulation, error)
+	DeriveColorScheme(ctx context.Context, username string, req models.DeriveRequest) (*models.DerivedColorScheme, error)
+	CreateVariant(ctx context.Context, username, id string) (*models.ColorScheme, error)
+	GenerateColorScheme(ctx context.Context, username string, req models.GenerateRequest) (*models.GeneratedColorScheme, error)
+	ExtractColorScheme(ctx context.Context, username string, req models.ExtractRequest, image io.Reader) (*models.ExtractedColorScheme, error)
+	SimilarColorSchemes(ctx context.Context, username, id string, limit int) ([]models.SimilarColorScheme, error)
+	DiffColorSchemes(ctx context.Context, username, fromID, toID string) (*models.SchemeDiff, error)
+	MergeColorScheme(ctx context.Context, username, id string, req models.MergeRequest) (*models.MergeResult, error)
+	MapToXterm(ctx context.Context, username, id string) (*models.XtermReport, error)
+	ListRevisions(ctx context.Context, username, id string) ([]models.Revision, error)
+	GetRevision(ctx context.Context, username, id string, revision int) (*models.Revision, error)
+	RestoreRevision(ctx context.Context, username, id string, revision int) (*models.ColorScheme, error)
+	ForkColorScheme(ctx context.Context, username, id string, req models.ForkRequest) (*models.ColorScheme, error)
+	GetLineage(ctx context.Context, username, id string) (*models.Lineage, error)
+	ListTags(ctx context.Context, username, prefix string, limit int) ([]models.TagCount, error)
+	GetColorSchemesByTag(ctx context.Context, username, tag string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	StarColorScheme(ctx context.Context, username, id string) (*models.StarStatus, error)
+	UnstarColorScheme(ctx context.Context, username, id string) (*models.StarStatus, error)
+	GetStarredColorSchemes(ctx context.Context, username string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+	GetTrendingColorSchemes(ctx context.Context, limit int) ([]models.TrendingColorScheme, error)
+	ExportColorScheme(ctx context.Context, username, id, format string) (*models.ExportFile, error)
+	TransferColorScheme(ctx context.Context, username, id string, org *string) (*models.ColorScheme, error)
 }
 
 type colorSchemeService struct {
 	colorSchemeRepo repository.ColorSchemeRepository
+	orgRepo         repository.OrganizationRepository
 	log             zerolog.Logger
 }
 
-func NewColorSchemeService(colorSchemeRepo repository.ColorSchemeRepository, log zerolog.Logger) ColorSchemeService {
+func NewColorSchemeService(colorSchemeRepo repository.ColorSchemeRepository, orgRepo repository.OrganizationRepository, log zerolog.Logger) ColorSchemeService {
 	return &colorSchemeService{
 		colorSchemeRepo: colorSchemeRepo,
+		orgRepo:         orgRepo,
 		log:             log,
 	}
 }
 
-func (s *colorSchemeService) GetAllColorSchemesByAuthor(ctx context.Context, author string) ([]models.ColorScheme, error) {
-	colorSchemes, err := s.colorSchemeRepo.GetByAuthor(ctx, author)
+func (s *colorSchemeService) GetAllColorSchemesByAuthor(ctx context.Context, author string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	colorSchemes, err := s.colorSchemeRepo.GetByAuthor(ctx, author, filter)
 	if err != nil {
 		s.log.Error().Err(err).Str("author", author).Msg("Failed to get all color schemes")
 		return nil, err
@@ -43,43 +127,1080 @@ func (s *colorSchemeService) GetAllColorSchemesByAuthor(ctx context.Context, aut
 	return colorSchemes, nil
 }
 
-func (s *colorSchemeService) GetColorSchemeById(ctx context.Context, id string) (*models.ColorScheme, error) {
+// GetColorSchemeById returns a scheme username can see, see getVisible.
+func (s *colorSchemeService) GetColorSchemeById(ctx context.Context, username, id string) (*models.ColorScheme, error) {
+	return s.getVisible(ctx, username, id)
+}
+
+// get loads a scheme by ID without checking who may see it.
+func (s *colorSchemeService) get(ctx context.Context, id string) (*models.ColorScheme, error) {
 	colorScheme, err := s.colorSchemeRepo.GetById(ctx, id)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrColorSchemeNotFound
+		}
 		s.log.Error().Err(err).Str("id", id).Msg("Failed to get color scheme")
 		return nil, err
 	}
 
 	if colorScheme == nil {
-		return nil, errors.New("color scheme not found")
+		return nil, ErrColorSchemeNotFound
 	}
 
 	return colorScheme, nil
 }
 
-func (s *colorSchemeService) CreateColorScheme(ctx context.Context, colorScheme models.ColorScheme) error {
-	if err := s.colorSchemeRepo.Create(ctx, colorScheme); err != nil {
+// CreateColorScheme stores colorScheme as owned by username regardless of
+// the author sent by the client, or by its organization when set, which
+// needs username to be an editor there.
+func (s *colorSchemeService) CreateColorScheme(ctx context.Context, username string, colorScheme models.ColorScheme) (*models.ColorScheme, error) {
+	if colorScheme.Organization != nil {
+		a, err := s.access(ctx, username)
+		if err != nil {
+			return nil, err
+		}
+		if !a.canEdit(username, colorScheme.Organization) {
+			return nil, ErrForbidden
+		}
+	}
+
+	colorScheme.Author = username
+	colorScheme.Version = 1
+	colorScheme.Stars = 0
+	if err := normalizeTags(&colorScheme); err != nil {
+		return nil, err
+	}
+	if err := validateColors(colorScheme); err != nil {
+		return nil, err
+	}
+	s.classify(&colorScheme)
+
+	features, err := palette.Features(colorScheme)
+	if err == nil {
+		colorScheme.DuplicateOf = s.findNearDuplicate(ctx, username, *features)
+	}
+
+	if err := s.colorSchemeRepo.Create(ctx, colorScheme, username); err != nil {
 		s.log.Error().Err(err).Msg("Failed to create color scheme")
-		return err
+		return nil, err
 	}
 
-	return nil
+	s.refreshAccessibility(ctx, &colorScheme)
+	s.refreshFeatures(ctx, &colorScheme)
+	return &colorScheme, nil
 }
 
-func (s *colorSchemeService) UpdateColorScheme(ctx context.Context, colorScheme models.ColorScheme) error {
-	if err := s.colorSchemeRepo.Update(ctx, colorScheme); err != nil {
+// UpdateColorScheme replaces the scheme if it is still at
+// colorScheme.Version, models.AnyVersion skips the check. A stale version
+// returns a *VersionConflictError. Nil tags keep the current ones.
+func (s *colorSchemeService) UpdateColorScheme(ctx context.Context, username string, colorScheme models.ColorScheme) (*models.ColorScheme, error) {
+	updated, _, err := s.update(ctx, username, colorScheme)
+	return updated, err
+}
+
+// update is UpdateColorScheme, also returning the revision it recorded.
+func (s *colorSchemeService) update(ctx context.Context, username string, colorScheme models.ColorScheme) (*models.ColorScheme, int, error) {
+	existing, err := s.authorize(ctx, username, colorScheme.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if colorScheme.Version != models.AnyVersion && colorScheme.Version != existing.Version {
+		return nil, 0, &VersionConflictError{Current: existing.Version}
+	}
+
+	colorScheme.Author = existing.Author
+	colorScheme.Organization = existing.Organization
+	colorScheme.Version = existing.Version
+	colorScheme.VariantID = existing.VariantID
+	colorScheme.DuplicateOf = existing.DuplicateOf
+	colorScheme.ForkedFrom = existing.ForkedFrom
+	colorScheme.ForkedRevision = existing.ForkedRevision
+	colorScheme.Stars = existing.Stars
+	if colorScheme.Tags == nil {
+		colorScheme.Tags = existing.Tags
+	}
+	if err := normalizeTags(&colorScheme); err != nil {
+		return nil, 0, err
+	}
+	if err := validateColors(colorScheme); err != nil {
+		return nil, 0, err
+	}
+	s.classify(&colorScheme)
+	revision, err := s.colorSchemeRepo.Update(ctx, colorScheme, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, 0, s.versionConflict(ctx, colorScheme.ID)
+		}
 		s.log.Error().Err(err).Msg("Failed to update color scheme")
+		return nil, 0, err
+	}
+	colorScheme.Version++
+
+	s.refreshAccessibility(ctx, &colorScheme)
+	s.refreshFeatures(ctx, &colorScheme)
+	return &colorScheme, revision, nil
+}
+
+// PatchColorScheme applies a JSON Merge Patch or JSON Patch, picked by
+// contentType, to the JSON form of the scheme and saves the result as
+// UpdateColorScheme does. The ID, author and version can't be patched.
+func (s *colorSchemeService) PatchColorScheme(ctx context.Context, username, id string, version int, contentType string, patch []byte) (*models.ColorScheme, error) {
+	existing, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if version != models.AnyVersion && version != existing.Version {
+		return nil, &VersionConflictError{Current: existing.Version}
+	}
+
+	doc, err := json.Marshal(existing)
+	if err != nil {
+		return nil, err
+	}
+
+	switch contentType {
+	case models.MergePatchContentType:
+		doc, err = jsonpatch.MergePatch(doc, patch)
+	case models.JSONPatchContentType:
+		doc, err = jsonpatch.Apply(doc, patch)
+	default:
+		err = fmt.Errorf("unsupported patch type %q", contentType)
+	}
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "patch", Code: "invalid", Message: err.Error()}}}
+	}
+
+	var patched models.ColorScheme
+	if err := json.Unmarshal(doc, &patched); err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "patch", Code: "invalid", Message: err.Error()}}}
+	}
+
+	patched.ID = existing.ID
+	patched.Version = existing.Version
+	return s.UpdateColorScheme(ctx, username, patched)
+}
+
+// DeleteColorScheme deletes the scheme if it is still at version, see
+// UpdateColorScheme.
+func (s *colorSchemeService) DeleteColorScheme(ctx context.Context, username, id string, version int) error {
+	existing, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return err
+	}
+
+	if version != models.AnyVersion && version != existing.Version {
+		return &VersionConflictError{Current: existing.Version}
+	}
+
+	if err := s.colorSchemeRepo.Delete(ctx, id, existing.Version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return s.versionConflict(ctx, id)
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to delete color scheme")
 		return err
 	}
 
 	return nil
 }
 
-func (s *colorSchemeService) DeleteColorScheme(ctx context.Context, id string) error {
-	if err := s.colorSchemeRepo.Delete(ctx, id); err != nil {
-		s.log.Error().Err(err).Str("id", id).Msg("Failed to delete color scheme")
+// versionConflict reports the current version of a scheme whose
+// conditional write matched no row, or that it is gone.
+func (s *colorSchemeService) versionConflict(ctx context.Context, id string) error {
+	current, err := s.get(ctx, id)
+	if err != nil {
 		return err
 	}
+	return &VersionConflictError{Current: current.Version}
+}
+
+// ListRevisions returns the scheme's revisions, newest first.
+func (s *colorSchemeService) ListRevisions(ctx context.Context, username, id string) ([]models.Revision, error) {
+	if _, err := s.getVisible(ctx, username, id); err != nil {
+		return nil, err
+	}
+
+	revisions, err := s.colorSchemeRepo.ListRevisions(ctx, id)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to list color scheme revisions")
+		return nil, err
+	}
+
+	if len(revisions) == 0 {
+		revisions = []models.Revision{}
+	}
+
+	return revisions, nil
+}
+
+func (s *colorSchemeService) GetRevision(ctx context.Context, username, id string, revision int) (*models.Revision, error) {
+	if _, err := s.getVisible(ctx, username, id); err != nil {
+		return nil, err
+	}
+
+	rev, err := s.colorSchemeRepo.GetRevision(ctx, id, revision)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRevisionNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Int("revision", revision).Msg("Failed to get color scheme revision")
+		return nil, err
+	}
+
+	return rev, nil
+}
+
+// RestoreRevision brings back the name and colors of an earlier revision.
+// The restore is saved as a new revision, later ones are kept.
+func (s *colorSchemeService) RestoreRevision(ctx context.Context, username, id string, revision int) (*models.ColorScheme, error) {
+	colorScheme, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	rev, err := s.GetRevision(ctx, username, id, revision)
+	if err != nil {
+		return nil, err
+	}
+
+	colorScheme.Name = rev.Name
+	colorScheme.Category = rev.Category
+	colorScheme.Colors = rev.Colors
+	return s.UpdateColorScheme(ctx, username, *colorScheme)
+}
+
+// getVisible loads a scheme username may see, a public one, their own or
+// one of an organization they belong to.
+// Hidden schemes are reported as not found.
+func (s *colorSchemeService) getVisible(ctx context.Context, username, id string) (*models.ColorScheme, error) {
+	colorScheme, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if colorScheme.Public {
+		return colorScheme, nil
+	}
+
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if !a.canView(colorScheme.Public, colorScheme.Author, colorScheme.Organization) {
+		return nil, ErrColorSchemeNotFound
+	}
+
+	return colorScheme, nil
+}
+
+func (s *colorSchemeService) access(ctx context.Context, username string) (access, error) {
+	a, err := loadAccess(ctx, s.orgRepo, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get organization roles")
+	}
+	return a, err
+}
+
+// authorize loads the scheme and checks that username may modify it: it is
+// their personal scheme or they are an editor or owner of the organization
+// owning it. Moderation of other users' schemes goes through AdminService.
+func (s *colorSchemeService) authorize(ctx context.Context, username, id string) (*models.ColorScheme, error) {
+	colorScheme, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if colorScheme.Organization == nil {
+		if colorScheme.Author != username {
+			return nil, ErrForbidden
+		}
+		return colorScheme, nil
+	}
+
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if !a.canEdit(colorScheme.Author, colorScheme.Organization) {
+		return nil, ErrForbidden
+	}
+
+	return colorScheme, nil
+}
+
+// GetAccessibilityReport returns the stored report of a scheme username
+// can see, computing it first for schemes saved before reports existed.
+func (s *colorSchemeService) GetAccessibilityReport(ctx context.Context, username, id string) (*models.AccessibilityReport, error) {
+	colorScheme, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := s.colorSchemeRepo.GetAccessibility(ctx, id)
+	if err == nil {
+		return report, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get accessibility report")
+		return nil, err
+	}
+
+	report, err = palette.Accessibility(*colorScheme)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	if err := s.colorSchemeRepo.SaveAccessibility(ctx, *report); err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to save accessibility report")
+	}
+
+	return report, nil
+}
+
+// classify sets the scheme's metrics and category from its colors. Schemes
+// whose background or foreground can't be parsed keep the category sent by
+// the client and get no metrics.
+func (s *colorSchemeService) classify(colorScheme *models.ColorScheme) {
+	metrics, category, err := palette.Classify(*colorScheme)
+	if err != nil {
+		s.log.Warn().Err(err).Str("id", colorScheme.ID).Msg("Skipping color scheme classification")
+		colorScheme.Metrics = nil
+		return
+	}
+
+	colorScheme.Metrics = metrics
+	colorScheme.Category = category
+}
+
+// refreshAccessibility recomputes and stores the report after a write. A
+// failure here must not fail the write itself: Update has already dropped
+// the old report, so the next read rebuilds it.
+func (s *colorSchemeService) refreshAccessibility(ctx context.Context, colorScheme *models.ColorScheme) {
+	report, err := palette.Accessibility(*colorScheme)
+	if err != nil {
+		s.log.Warn().Err(err).Str("id", colorScheme.ID).Msg("Skipping accessibility report")
+		return
+	}
+
+	if err := s.colorSchemeRepo.SaveAccessibility(ctx, *report); err != nil {
+		s.log.Error().Err(err).Str("id", colorScheme.ID).Msg("Failed to save accessibility report")
+		return
+	}
+
+	colorScheme.AccessibilityScore = &report.Score
+}
+
+// refreshFeatures stores the scheme's similarity features after a write.
+// Like the accessibility report it is best effort, a scheme without
+// features only drops out of similarity results.
+func (s *colorSchemeService) refreshFeatures(ctx context.Context, colorScheme *models.ColorScheme) {
+	features, err := palette.Features(*colorScheme)
+	if err != nil {
+		s.log.Warn().Err(err).Str("id", colorScheme.ID).Msg("Skipping similarity features")
+		return
+	}
+
+	if err := s.colorSchemeRepo.SaveFeatures(ctx, *features); err != nil {
+		s.log.Error().Err(err).Str("id", colorScheme.ID).Msg("Failed to save similarity features")
+	}
+}
+
+// SimilarColorSchemes ranks the schemes visible to username by their
+// perceptual distance from the scheme id, closest first. Only schemes whose
+// background lightness is within similarityWindow of the source's are
+// compared, which keeps the search on the background_l index.
+func (s *colorSchemeService) SimilarColorSchemes(ctx context.Context, username, id string, limit int) ([]models.SimilarColorScheme, error) {
+	source, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	features, err := palette.Features(*source)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	ranked, err := s.rankSimilar(ctx, username, *features)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to find similar color schemes")
+		return nil, err
+	}
+	if len(ranked) > limit {
+		ranked = ranked[:limit]
+	}
+
+	similar := make([]models.SimilarColorScheme, 0, len(ranked))
+	for _, r := range ranked {
+		colorScheme, err := s.colorSchemeRepo.GetById(ctx, r.id)
+		if err != nil {
+			// Deleted since the features were read
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			s.log.Error().Err(err).Str("id", r.id).Msg("Failed to get similar color scheme")
+			return nil, err
+		}
+		similar = append(similar, models.SimilarColorScheme{
+			Scheme:        *colorScheme,
+			Distance:      r.distance,
+			NearDuplicate: r.distance < palette.NearDuplicateDistance,
+		})
+	}
+
+	return similar, nil
+}
+
+type rankedScheme struct {
+	id       string
+	distance float64
+}
+
+// rankSimilar compares features with every candidate visible to username and
+// returns them sorted by distance. The source scheme itself is skipped.
+func (s *colorSchemeService) rankSimilar(ctx context.Context, username string, features models.SchemeFeatures) ([]rankedScheme, error) {
+	candidates, err := s.colorSchemeRepo.FindFeatures(ctx, username, features.BackgroundL-similarityWindow, features.BackgroundL+similarityWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	var ranked []rankedScheme
+	for _, c := range candidates {
+		if c.SchemeID == features.SchemeID {
+			continue
+		}
+		if d, ok := palette.Distance(features, c); ok {
+			ranked = append(ranked, rankedScheme{id: c.SchemeID, distance: d})
+		}
+	}
+
+	sort.Slice(ranked, func(i, j int) bool { return ranked[i].distance < ranked[j].distance })
+	return ranked, nil
+}
+
+// findNearDuplicate returns the ID of the closest visible scheme when it is
+// a near-duplicate of features. Lookup errors are logged and ignored, they
+// must not block the submission.
+func (s *colorSchemeService) findNearDuplicate(ctx context.Context, username string, features models.SchemeFeatures) *string {
+	ranked, err := s.rankSimilar(ctx, username, features)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to check for near-duplicate color schemes")
+		return nil
+	}
+
+	if len(ranked) == 0 || ranked[0].distance >= palette.NearDuplicateDistance {
+		return nil
+	}
+	return &ranked[0].id
+}
+
+// DiffColorSchemes compares two schemes visible to username slot by slot.
+func (s *colorSchemeService) DiffColorSchemes(ctx context.Context, username, fromID, toID string) (*models.SchemeDiff, error) {
+	from, err := s.getVisible(ctx, username, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getVisible(ctx, username, toID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, unchanged := palette.Diff(from.Colors, to.Colors)
+	if changes == nil {
+		changes = []models.ColorChange{}
+	}
+
+	return &models.SchemeDiff{
+		From:      from.ID,
+		To:        to.ID,
+		Changes:   changes,
+		Unchanged: unchanged,
+	}, nil
+}
+
+// MergeColorScheme pulls the changes made to an upstream scheme since
+// req.Base into the scheme id, keeping local edits, and saves the result
+// unless req.DryRun is set or nothing changed.
+func (s *colorSchemeService) MergeColorScheme(ctx context.Context, username, id string, req models.MergeRequest) (*models.MergeResult, error) {
+	for key, resolution := range req.Resolutions {
+		if resolution != palette.ResolveLocal && resolution != palette.ResolveUpstream {
+			return nil, &ValidationError{Fields: []models.FieldError{{Field: "resolutions." + key, Code: "invalid", Message: "Resolution must be local or upstream"}}}
+		}
+	}
+
+	local, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	upstreamID := req.UpstreamID
+	if upstreamID == "" {
+		if local.ForkedFrom == nil {
+			return nil, &ValidationError{Fields: []models.FieldError{{Field: "upstream_id", Code: "required", Message: "Upstream is required for schemes that are not forks"}}}
+		}
+		upstreamID = *local.ForkedFrom
+	}
+	upstream, err := s.getVisible(ctx, username, upstreamID)
+	if err != nil {
+		return nil, err
+	}
+	fromParent := local.ForkedFrom != nil && *local.ForkedFrom == upstream.ID
+
+	base := req.Base
+	if base == nil {
+		if !fromParent || local.ForkedRevision == nil {
+			return nil, &ValidationError{Fields: []models.FieldError{{Field: "base", Code: "required", Message: "Base is required unless merging from the scheme this one was forked from"}}}
+		}
+		rev, err := s.GetRevision(ctx, username, upstream.ID, *local.ForkedRevision)
+		if err != nil {
+			return nil, err
+		}
+		base = rev.Colors
+	}
+
+	merged, applied, conflicts := palette.Merge(base, local.Colors, upstream.Colors, req.Resolutions)
+	if applied == nil {
+		applied = []string{}
+	}
+	if conflicts == nil {
+		conflicts = []models.MergeConflict{}
+	}
+
+	local.Colors = merged
+	if !req.DryRun && len(applied) > 0 {
+		if local, err = s.UpdateColorScheme(ctx, username, *local); err != nil {
+			return nil, err
+		}
+	}
+
+	// The next merge from the parent starts from what was merged now
+	if !req.DryRun && fromParent {
+		latest, err := s.colorSchemeRepo.LatestRevision(ctx, upstream.ID)
+		if err == nil {
+			err = s.colorSchemeRepo.SetForkedRevision(ctx, local.ID, latest)
+		}
+		if err != nil {
+			s.log.Error().Err(err).Str("id", local.ID).Msg("Failed to advance forked revision")
+			return nil, err
+		}
+		local.ForkedRevision = latest
+	}
+
+	return &models.MergeResult{
+		Scheme:    *local,
+		Applied:   applied,
+		Conflicts: conflicts,
+	}, nil
+}
 
+// normalizeTags normalizes and deduplicates the scheme's tags in place.
+func normalizeTags(colorScheme *models.ColorScheme) error {
+	normalized, err := tags.NormalizeAll(colorScheme.Tags)
+	if err != nil {
+		return &ValidationError{Fields: []models.FieldError{{Field: "tags", Code: "invalid", Message: err.Error()}}}
+	}
+	colorScheme.Tags = normalized
+	return nil
+}
+
+// validateColors rejects schemes with a color that doesn't parse, which
+// would otherwise be stored with NaN metrics that can't be served as JSON.
+func validateColors(colorScheme models.ColorScheme) error {
+	var fields []models.FieldError
+	for _, key := range slices.Sorted(maps.Keys(colorScheme.Colors)) {
+		if _, err := color.Parse(colorScheme.Colors[key]); err != nil {
+			fields = append(fields, models.FieldError{Field: "colors." + key, Code: "invalid", Message: err.Error()})
+		}
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
 	return nil
 }
+
+// ListTags returns the tags starting with prefix on schemes username can
+// see, with the number of those schemes carrying each, most used first.
+func (s *colorSchemeService) ListTags(ctx context.Context, username, prefix string, limit int) ([]models.TagCount, error) {
+	counts, err := s.colorSchemeRepo.ListTags(ctx, username, prefix, limit)
+	if err != nil {
+		s.log.Error().Err(err).Str("prefix", prefix).Msg("Failed to list tags")
+		return nil, err
+	}
+
+	if len(counts) == 0 {
+		counts = []models.TagCount{}
+	}
+
+	return counts, nil
+}
+
+// GetColorSchemesByTag lists the schemes username can see that carry tag
+// and match filter.
+func (s *colorSchemeService) GetColorSchemesByTag(ctx context.Context, username, tag string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	var err error
+	if filter.Tags, err = tags.NormalizeAll(append(filter.Tags, tag)); err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "tag", Code: "invalid", Message: err.Error()}}}
+	}
+
+	colorSchemes, err := s.colorSchemeRepo.GetVisible(ctx, username, filter)
+	if err != nil {
+		s.log.Error().Err(err).Str("tag", tag).Msg("Failed to get color schemes by tag")
+		return nil, err
+	}
+
+	if len(colorSchemes) == 0 {
+		colorSchemes = []models.ColorScheme{}
+	}
+
+	return colorSchemes, nil
+}
+
+// StarColorScheme stars a public scheme for username. Starring it again
+// changes nothing.
+func (s *colorSchemeService) StarColorScheme(ctx context.Context, username, id string) (*models.StarStatus, error) {
+	colorScheme, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !colorScheme.Public {
+		return nil, ErrForbidden
+	}
+
+	stars, err := s.colorSchemeRepo.Star(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrColorSchemeNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to star color scheme")
+		return nil, err
+	}
+
+	return &models.StarStatus{SchemeID: id, Starred: true, Stars: stars}, nil
+}
+
+// UnstarColorScheme removes username's star. It also works on schemes made
+// private since, and succeeds when there was no star.
+func (s *colorSchemeService) UnstarColorScheme(ctx context.Context, username, id string) (*models.StarStatus, error) {
+	if _, err := s.get(ctx, id); err != nil {
+		return nil, err
+	}
+
+	stars, err := s.colorSchemeRepo.Unstar(ctx, id, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrColorSchemeNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to unstar color scheme")
+		return nil, err
+	}
+
+	return &models.StarStatus{SchemeID: id, Starred: false, Stars: stars}, nil
+}
+
+func (s *colorSchemeService) GetStarredColorSchemes(ctx context.Context, username string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	colorSchemes, err := s.colorSchemeRepo.GetStarred(ctx, username, filter)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get starred color schemes")
+		return nil, err
+	}
+
+	if len(colorSchemes) == 0 {
+		colorSchemes = []models.ColorScheme{}
+	}
+
+	return colorSchemes, nil
+}
+
+// GetTrendingColorSchemes ranks public schemes by recent stars, see
+// trendingHalfLife.
+func (s *colorSchemeService) GetTrendingColorSchemes(ctx context.Context, limit int) ([]models.TrendingColorScheme, error) {
+	trending, err := s.colorSchemeRepo.GetTrending(ctx, trendingHalfLife, trendingWindow, limit)
+	if err != nil {
+		s.log.Error().Err(err).Msg("Failed to get trending color schemes")
+		return nil, err
+	}
+
+	if len(trending) == 0 {
+		trending = []models.TrendingColorScheme{}
+	}
+
+	return trending, nil
+}
+
+// ForkColorScheme copies a scheme visible to username into their account,
+// recording the source and its current revision. The fork starts private.
+func (s *colorSchemeService) ForkColorScheme(ctx context.Context, username, id string, req models.ForkRequest) (*models.ColorScheme, error) {
+	source, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	revision, err := s.colorSchemeRepo.LatestRevision(ctx, source.ID)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get latest revision")
+		return nil, err
+	}
+
+	fork := models.ColorScheme{
+		Name:           source.Name,
+		Author:         username,
+		Category:       source.Category,
+		Colors:         make(map[string]string, len(source.Colors)),
+		Version:        1,
+		Tags:           source.Tags,
+		ForkedFrom:     &source.ID,
+		ForkedRevision: revision,
+	}
+	if req.Name != "" {
+		fork.Name = req.Name
+	}
+	for key, value := range source.Colors {
+		fork.Colors[key] = value
+	}
+
+	if fork.ID, err = utils.GenerateID(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to generate color scheme id")
+		return nil, err
+	}
+	s.classify(&fork)
+
+	if err := s.colorSchemeRepo.Create(ctx, fork, username); err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to fork color scheme")
+		return nil, err
+	}
+
+	s.refreshAccessibility(ctx, &fork)
+	s.refreshFeatures(ctx, &fork)
+	return &fork, nil
+}
+
+// TransferColorScheme moves a scheme username may modify into org, where
+// they must be an editor, or back to a personal scheme of theirs when org
+// is nil, which for an organization's scheme needs them to be its owner.
+func (s *colorSchemeService) TransferColorScheme(ctx context.Context, username, id string, org *string) (*models.ColorScheme, error) {
+	colorScheme, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if colorScheme.Organization != nil && a.roles[*colorScheme.Organization] != models.OrgRoleOwner {
+		return nil, ErrForbidden
+	}
+	if org != nil && !a.canEdit(username, org) {
+		return nil, ErrForbidden
+	}
+
+	if err := s.colorSchemeRepo.SetOwner(ctx, id, username, org); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrColorSchemeNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to transfer color scheme")
+		return nil, err
+	}
+
+	s.log.Info().Str("actor", username).Str("id", id).Msg("Color scheme transferred")
+	return s.get(ctx, id)
+}
+
+// ExportColorScheme writes a scheme username can see as a terminal
+// configuration file.
+func (s *colorSchemeService) ExportColorScheme(ctx context.Context, username, id, format string) (*models.ExportFile, error) {
+	f, err := lookupExportFormat(format)
+	if err != nil {
+		return nil, err
+	}
+
+	colorScheme, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := f.Export(*colorScheme)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	return &models.ExportFile{Name: f.FileName(*colorScheme), ContentType: f.ContentType, Data: data}, nil
+}
+
+func lookupExportFormat(format string) (palette.ExportFormat, error) {
+	f, ok := palette.LookupExportFormat(format)
+	if !ok {
+		message := "format must be one of " + strings.Join(palette.ExportFormatNames(), ", ")
+		return f, &ValidationError{Fields: []models.FieldError{{Field: "format", Code: "invalid", Message: message}}}
+	}
+	return f, nil
+}
+
+// GetLineage returns the ancestors and direct forks of a scheme that
+// username can see.
+func (s *colorSchemeService) GetLineage(ctx context.Context, username, id string) (*models.Lineage, error) {
+	if _, err := s.getVisible(ctx, username, id); err != nil {
+		return nil, err
+	}
+
+	ancestors, err := s.colorSchemeRepo.GetAncestors(ctx, id)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get color scheme ancestors")
+		return nil, err
+	}
+	forks, err := s.colorSchemeRepo.GetForks(ctx, id)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get color scheme forks")
+		return nil, err
+	}
+
+	a, err := s.access(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := func(entries []models.LineageEntry) []models.LineageEntry {
+		out := []models.LineageEntry{}
+		for _, e := range entries {
+			if a.canView(e.Public, e.Author, e.Organization) {
+				out = append(out, e)
+			}
+		}
+		return out
+	}
+
+	return &models.Lineage{
+		Ancestors: visible(ancestors),
+		Forks:     visible(forks),
+	}, nil
+}
+
+// MapToXterm maps the scheme onto the xterm 256 and 16 color palettes for
+// terminals without truecolor.
+func (s *colorSchemeService) MapToXterm(ctx context.Context, username, id string) (*models.XtermReport, error) {
+	colorScheme, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := palette.Xterm(*colorScheme)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	return report, nil
+}
+
+// SimulateColorVision simulates a scheme username can see under one
+// deficiency, or under every supported deficiency when deficiency is empty.
+func (s *colorSchemeService) SimulateColorVision(ctx context.Context, username, id, deficiency string, severity float64) ([]models.CVDSimulation, error) {
+	deficiencies := color.Deficiencies
+	if deficiency != "" {
+		d := color.Deficiency(deficiency)
+		if !d.Valid() {
+			return nil, &ValidationError{Fields: []models.FieldError{{Field: "type", Code: "invalid", Message: "Unknown color vision deficiency"}}}
+		}
+		deficiencies = []color.Deficiency{d}
+	}
+
+	if math.IsNaN(severity) || severity < 0 || severity > 1 {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "severity", Code: "range", Message: "Severity must be between 0 and 1"}}}
+	}
+
+	colorScheme, err := s.getVisible(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	simulations := make([]models.CVDSimulation, 0, len(deficiencies))
+	for _, d := range deficiencies {
+		simulations = append(simulations, *palette.SimulateCVD(*colorScheme, d, severity))
+	}
+
+	return simulations, nil
+}
+
+// DeriveColorScheme fills in the missing colors of a partial palette. The
+// result is not saved.
+func (s *colorSchemeService) DeriveColorScheme(ctx context.Context, username string, req models.DeriveRequest) (*models.DerivedColorScheme, error) {
+	colors, derived, err := palette.Derive(req.Colors)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	scheme := models.ColorScheme{
+		Name:     req.Name,
+		Author:   username,
+		Category: req.Category,
+		Colors:   colors,
+	}
+	s.classify(&scheme)
+
+	return &models.DerivedColorScheme{
+		Scheme:  scheme,
+		Derived: derived,
+	}, nil
+}
+
+// CreateVariant generates and saves the light companion of a dark scheme or
+// the dark companion of a light one, linking the two.
+func (s *colorSchemeService) CreateVariant(ctx context.Context, username, id string) (*models.ColorScheme, error) {
+	source, err := s.authorize(ctx, username, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if source.VariantID != nil {
+		return nil, ErrVariantExists
+	}
+
+	variant, err := palette.Variant(*source)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "colors", Code: "invalid", Message: err.Error()}}}
+	}
+
+	if variant.ID, err = utils.GenerateID(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to generate color scheme id")
+		return nil, err
+	}
+	variant.Author = source.Author
+	variant.Organization = source.Organization
+	variant.Version = 1
+	variant.VariantID = &source.ID
+	s.classify(&variant)
+
+	if err := s.colorSchemeRepo.CreateVariant(ctx, source.ID, variant, username); err != nil {
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to create color scheme variant")
+		return nil, err
+	}
+
+	s.refreshAccessibility(ctx, &variant)
+	s.refreshFeatures(ctx, &variant)
+	return &variant, nil
+}
+
+// GenerateColorScheme builds a scheme from seed colors and a harmony rule.
+// Without an explicit seed a random one is picked and returned so the result
+// can be reproduced. The result is not saved.
+func (s *colorSchemeService) GenerateColorScheme(ctx context.Context, username string, req models.GenerateRequest) (*models.GeneratedColorScheme, error) {
+	var fields []models.FieldError
+
+	seeds := make([]color.RGB, 0, len(req.Seeds))
+	for _, v := range req.Seeds {
+		c, err := color.Parse(v)
+		if err != nil {
+			fields = append(fields, models.FieldError{Field: "seeds", Code: "invalid", Message: err.Error()})
+			continue
+		}
+		seeds = append(seeds, c)
+	}
+
+	harmony := palette.Harmony(req.Harmony)
+	if harmony == "" {
+		harmony = palette.Analogous
+	}
+	if !harmony.Valid() {
+		fields = append(fields, models.FieldError{Field: "harmony", Code: "invalid", Message: "Harmony must be analogous, triadic, complementary or tinted-monochrome"})
+	}
+
+	if req.Mode != "" && req.Mode != "dark" && req.Mode != "light" {
+		fields = append(fields, models.FieldError{Field: "mode", Code: "invalid", Message: "Mode must be dark or light"})
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	seed := rand.Int64()
+	if req.Seed != nil {
+		seed = *req.Seed
+	}
+
+	colors, err := palette.Generate(palette.GenerateOptions{
+		Seeds:   seeds,
+		Harmony: harmony,
+		Dark:    req.Mode != "light",
+		Seed:    seed,
+	})
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "seeds", Code: "invalid", Message: err.Error()}}}
+	}
+
+	scheme := models.ColorScheme{
+		Name:   req.Name,
+		Author: username,
+		Colors: colors,
+	}
+	s.classify(&scheme)
+
+	return &models.GeneratedColorScheme{
+		Scheme:  scheme,
+		Harmony: string(harmony),
+		Seed:    seed,
+	}, nil
+}
+
+// ExtractColorScheme builds a scheme matching a PNG or JPEG image, see
+// palette.Extract. Mode is "dark", "light" or empty to follow the image. The
+// result is not saved.
+func (s *colorSchemeService) ExtractColorScheme(ctx context.Context, username string, req models.ExtractRequest, r io.Reader) (*models.ExtractedColorScheme, error) {
+	var fields []models.FieldError
+
+	opts := palette.ExtractOptions{Clusters: req.Clusters}
+	switch req.Mode {
+	case "":
+	case "dark", "light":
+		dark := req.Mode == "dark"
+		opts.Dark = &dark
+	default:
+		fields = append(fields, models.FieldError{Field: "mode", Code: "invalid", Message: "Mode must be dark or light"})
+	}
+
+	if req.Clusters != 0 && (req.Clusters < minExtractClusters || req.Clusters > maxExtractClusters) {
+		fields = append(fields, models.FieldError{Field: "clusters", Code: "range", Message: "Clusters must be between 8 and 32"})
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	config, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "image", Code: "invalid", Message: "Image must be a PNG or JPEG"}}}
+	}
+	if config.Width*config.Height > maxImagePixels {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "image", Code: "too_large", Message: "Image has too many pixels"}}}
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "image", Code: "invalid", Message: "Image could not be decoded"}}}
+	}
+
+	colors, clusters, err := palette.Extract(img, opts)
+	if err != nil {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "image", Code: "invalid", Message: err.Error()}}}
+	}
+
+	scheme := models.ColorScheme{
+		Name:   req.Name,
+		Author: username,
+		Colors: colors,
+	}
+	s.classify(&scheme)
+
+	extracted := &models.ExtractedColorScheme{
+		Scheme:   scheme,
+		Clusters: make([]models.ExtractedCluster, len(clusters)),
+	}
+	for i, c := range clusters {
+		extracted.Clusters[i] = models.ExtractedCluster{Color: c.Color.Hex(), Weight: c.Weight}
+	}
+	return extracted, nil
+}
@@ -0,0 +1,333 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/color"
+	"github.com/nqvinh00/colorscheme/pkg/utils"
+	"github.com/nqvinh00/colorscheme/repository"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrCommentNotFound    = errors.New("comment not found")
+	ErrSuggestionResolved = errors.New("suggestion has already been resolved")
+)
+
+// Limits on what a comment may hold.
+const (
+	maxCommentBody       = 5000
+	maxSuggestionChanges = 64
+)
+
+type CommentService interface {
+	ListComments(ctx context.Context, username, schemeID string) ([]models.Comment, error)
+	CreateComment(ctx context.Context, username, schemeID string, req models.CommentRequest) (*models.Comment, error)
+	DeleteComment(ctx context.Context, username, schemeID, id string) error
+	AcceptSuggestion(ctx context.Context, username, schemeID, id string) (*models.Comment, error)
+	RejectSuggestion(ctx context.Context, username, schemeID, id string) (*models.Comment, error)
+}
+
+type commentService struct {
+	commentRepo repository.CommentRepository
+	orgRepo     repository.OrganizationRepository
+	// schemes applies accepted suggestions as any edit of the scheme
+	schemes *colorSchemeService
+	log     zerolog.Logger
+}
+
+func NewCommentService(commentRepo repository.CommentRepository, colorSchemeRepo repository.ColorSchemeRepository, orgRepo repository.OrganizationRepository, log zerolog.Logger) CommentService {
+	return &commentService{
+		commentRepo: commentRepo,
+		orgRepo:     orgRepo,
+		schemes: &colorSchemeService{
+			colorSchemeRepo: colorSchemeRepo,
+			orgRepo:         orgRepo,
+			log:             log,
+		},
+		log: log,
+	}
+}
+
+// ListComments returns the scheme's comments as threads, oldest first.
+// Deleted comments are only kept while they have replies.
+func (s *commentService) ListComments(ctx context.Context, username, schemeID string) ([]models.Comment, error) {
+	if _, _, err := s.getScheme(ctx, username, schemeID); err != nil {
+		return nil, err
+	}
+
+	comments, err := s.commentRepo.List(ctx, schemeID)
+	if err != nil {
+		s.log.Error().Err(err).Str("id", schemeID).Msg("Failed to list comments")
+		return nil, err
+	}
+
+	replies := make(map[string][]models.Comment)
+	var roots []models.Comment
+	for _, comment := range comments {
+		if comment.ParentID == nil {
+			roots = append(roots, comment)
+			continue
+		}
+		replies[*comment.ParentID] = append(replies[*comment.ParentID], comment)
+	}
+
+	return thread(roots, replies), nil
+}
+
+// thread attaches their replies to comments, dropping deleted comments left
+// without any.
+func thread(comments []models.Comment, replies map[string][]models.Comment) []models.Comment {
+	threaded := []models.Comment{}
+	for _, comment := range comments {
+		if r := replies[comment.ID]; len(r) > 0 {
+			comment.Replies = thread(r, replies)
+		}
+		if comment.Deleted && len(comment.Replies) == 0 {
+			continue
+		}
+		threaded = append(threaded, comment)
+	}
+	return threaded
+}
+
+// CreateComment posts a comment on a scheme username can see. A request
+// with changes is a suggestion, which has to start a thread of its own.
+func (s *commentService) CreateComment(ctx context.Context, username, schemeID string, req models.CommentRequest) (*models.Comment, error) {
+	colorScheme, _, err := s.getScheme(ctx, username, schemeID)
+	if err != nil {
+		return nil, err
+	}
+
+	var fields []models.FieldError
+
+	body := strings.TrimSpace(req.Body)
+	switch {
+	case body == "" && len(req.Changes) == 0:
+		fields = append(fields, models.FieldError{Field: "body", Code: "required", Message: "body is required"})
+	case len(body) > maxCommentBody:
+		fields = append(fields, models.FieldError{Field: "body", Code: "too_long", Message: fmt.Sprintf("body must be at most %d characters", maxCommentBody)})
+	}
+
+	// Values are stored as normalized hex, whatever format they came in
+	changes := make(map[string]string, len(req.Changes))
+	if len(req.Changes) > maxSuggestionChanges {
+		fields = append(fields, models.FieldError{Field: "changes", Code: "too_many", Message: fmt.Sprintf("a suggestion changes at most %d colors", maxSuggestionChanges)})
+	} else {
+		for key, value := range req.Changes {
+			if !suggestableKey(colorScheme, key) {
+				fields = append(fields, models.FieldError{Field: "changes." + key, Code: "unknown", Message: "unknown color " + key})
+				continue
+			}
+			c, err := color.Parse(value)
+			if err != nil {
+				fields = append(fields, models.FieldError{Field: "changes." + key, Code: "invalid", Message: err.Error()})
+				continue
+			}
+			changes[key] = c.Hex()
+		}
+	}
+
+	if req.ParentID != nil {
+		parent, err := s.commentRepo.GetById(ctx, *req.ParentID)
+		switch {
+		case errors.Is(err, sql.ErrNoRows) || (err == nil && (parent.SchemeID != schemeID || parent.Deleted)):
+			fields = append(fields, models.FieldError{Field: "parent_id", Code: "not_found", Message: "comment " + *req.ParentID + " not found"})
+		case err != nil:
+			s.log.Error().Err(err).Str("id", *req.ParentID).Msg("Failed to get comment")
+			return nil, err
+		case len(req.Changes) > 0:
+			fields = append(fields, models.FieldError{Field: "parent_id", Code: "invalid", Message: "suggestions can't be replies"})
+		}
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	comment := models.Comment{
+		SchemeID: schemeID,
+		ParentID: req.ParentID,
+		Author:   username,
+		Body:     body,
+		Kind:     models.CommentKindComment,
+	}
+	if len(req.Changes) > 0 {
+		comment.Kind = models.CommentKindSuggestion
+		comment.Changes = changes
+		comment.Status = models.SuggestionOpen
+	}
+
+	if comment.ID, err = utils.GenerateID(); err != nil {
+		s.log.Error().Err(err).Msg("Failed to generate comment id")
+		return nil, err
+	}
+
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		s.log.Error().Err(err).Str("id", schemeID).Msg("Failed to create comment")
+		return nil, err
+	}
+
+	return s.commentRepo.GetById(ctx, comment.ID)
+}
+
+// DeleteComment removes a comment's content. Its author and anyone who may
+// edit the scheme can delete it.
+func (s *commentService) DeleteComment(ctx context.Context, username, schemeID, id string) error {
+	_, canEdit, err := s.getScheme(ctx, username, schemeID)
+	if err != nil {
+		return err
+	}
+
+	comment, err := s.get(ctx, schemeID, id)
+	if err != nil {
+		return err
+	}
+
+	if comment.Author != username && !canEdit {
+		return ErrForbidden
+	}
+
+	if err := s.commentRepo.Delete(ctx, id); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrCommentNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to delete comment")
+		return err
+	}
+
+	return nil
+}
+
+// AcceptSuggestion applies a suggestion's changes to the scheme, saving a
+// new revision that the suggestion records. Only those who may edit the
+// scheme can accept it, and only while it is open.
+func (s *commentService) AcceptSuggestion(ctx context.Context, username, schemeID, id string) (*models.Comment, error) {
+	colorScheme, canEdit, err := s.getScheme(ctx, username, schemeID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, ErrForbidden
+	}
+
+	suggestion, err := s.getOpenSuggestion(ctx, schemeID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	colors := maps.Clone(colorScheme.Colors)
+	if colors == nil {
+		colors = make(map[string]string, len(suggestion.Changes))
+	}
+	maps.Copy(colors, suggestion.Changes)
+	colorScheme.Colors = colors
+
+	// The scheme is saved at the version the suggestion was checked against,
+	// so a concurrent edit fails rather than being overwritten
+	_, revision, err := s.schemes.update(ctx, username, *colorScheme)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.resolve(ctx, username, id, models.SuggestionAccepted, &revision)
+}
+
+// RejectSuggestion closes an open suggestion without changing the scheme.
+func (s *commentService) RejectSuggestion(ctx context.Context, username, schemeID, id string) (*models.Comment, error) {
+	_, canEdit, err := s.getScheme(ctx, username, schemeID)
+	if err != nil {
+		return nil, err
+	}
+	if !canEdit {
+		return nil, ErrForbidden
+	}
+
+	if _, err := s.getOpenSuggestion(ctx, schemeID, id); err != nil {
+		return nil, err
+	}
+
+	return s.resolve(ctx, username, id, models.SuggestionRejected, nil)
+}
+
+func (s *commentService) resolve(ctx context.Context, username, id string, status models.SuggestionStatus, revision *int) (*models.Comment, error) {
+	if err := s.commentRepo.Resolve(ctx, id, status, username, revision); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrSuggestionResolved
+		}
+		s.log.Error().Err(err).Str("id", id).Str("status", string(status)).Msg("Failed to resolve suggestion")
+		return nil, err
+	}
+
+	s.log.Info().Str("actor", username).Str("id", id).Str("status", string(status)).Msg("Suggestion resolved")
+	return s.commentRepo.GetById(ctx, id)
+}
+
+// suggestableKey reports whether a suggestion may set key: one of the ANSI
+// colors, the background or foreground, or another color the scheme has.
+func suggestableKey(colorScheme *models.ColorScheme, key string) bool {
+	if _, ok := colorScheme.Colors[key]; ok {
+		return true
+	}
+	return key == models.BackgroundKey || key == models.ForegroundKey || slices.Contains(models.ANSIColorKeys, key)
+}
+
+// getScheme loads a scheme username may see and whether they may edit it.
+// Hidden schemes are reported as not found.
+func (s *commentService) getScheme(ctx context.Context, username, schemeID string) (*models.ColorScheme, bool, error) {
+	colorScheme, err := s.schemes.getVisible(ctx, username, schemeID)
+	if err != nil {
+		return nil, false, err
+	}
+
+	a, err := loadAccess(ctx, s.orgRepo, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get organization roles")
+		return nil, false, err
+	}
+
+	return colorScheme, a.canEdit(colorScheme.Author, colorScheme.Organization), nil
+}
+
+// getOpenSuggestion loads a suggestion on the scheme that is still open.
+func (s *commentService) getOpenSuggestion(ctx context.Context, schemeID, id string) (*models.Comment, error) {
+	comment, err := s.get(ctx, schemeID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if comment.Kind != models.CommentKindSuggestion {
+		return nil, &ValidationError{Fields: []models.FieldError{{Field: "id", Code: "invalid", Message: "comment is not a suggestion"}}}
+	}
+	if comment.Deleted || comment.Status != models.SuggestionOpen {
+		return nil, ErrSuggestionResolved
+	}
+
+	return comment, nil
+}
+
+// get loads a comment on the scheme. Comments on other schemes are reported
+// as not found.
+func (s *commentService) get(ctx context.Context, schemeID, id string) (*models.Comment, error) {
+	comment, err := s.commentRepo.GetById(ctx, id)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrCommentNotFound
+		}
+		s.log.Error().Err(err).Str("id", id).Msg("Failed to get comment")
+		return nil, err
+	}
+
+	if comment.SchemeID != schemeID {
+		return nil, ErrCommentNotFound
+	}
+
+	return comment, nil
+}
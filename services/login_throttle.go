@@ -0,0 +1,156 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/config"
+	"github.com/nqvinh00/colorscheme/repository"
+	"github.com/rs/zerolog"
+)
+
+// LoginLockedError is returned while a username or IP is locked out after
+// too many failed logins.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return fmt.Sprintf("too many failed login attempts, retry after %s", e.RetryAfter.Round(time.Second))
+}
+
+type loginThrottle struct {
+	repo             repository.LoginAttemptRepository
+	log              zerolog.Logger
+	userFreeAttempts int
+	ipFreeAttempts   int
+	baseDelay        time.Duration
+	maxLockout       time.Duration
+	resetAfter       time.Duration
+}
+
+func newLoginThrottle(repo repository.LoginAttemptRepository, log zerolog.Logger, cfg config.LoginConfig) *loginThrottle {
+	t := &loginThrottle{
+		repo:             repo,
+		log:              log,
+		userFreeAttempts: cfg.UserFreeAttempts,
+		ipFreeAttempts:   cfg.IPFreeAttempts,
+		baseDelay:        time.Duration(cfg.BaseDelaySeconds) * time.Second,
+		maxLockout:       time.Duration(cfg.MaxLockoutMinutes) * time.Minute,
+		resetAfter:       time.Duration(cfg.ResetAfterMinutes) * time.Minute,
+	}
+
+	if t.userFreeAttempts <= 0 {
+		t.userFreeAttempts = 5
+	}
+	if t.ipFreeAttempts <= 0 {
+		t.ipFreeAttempts = 20
+	}
+	if t.baseDelay <= 0 {
+		t.baseDelay = 2 * time.Second
+	}
+	if t.maxLockout <= 0 {
+		t.maxLockout = 15 * time.Minute
+	}
+	if t.resetAfter <= 0 {
+		t.resetAfter = time.Hour
+	}
+
+	return t
+}
+
+// attempt counts a login attempt for the username and the IP before it is
+// made, and returns a *LoginLockedError without counting it if either is
+// locked out. Counting up front rather than on failure means a burst of
+// concurrent attempts can't all pass a check before any failure is
+// recorded. Attempts that don't fail are given back by release or succeed.
+func (t *loginThrottle) attempt(ctx context.Context, username, ip string) error {
+	resetBefore := time.Now().Add(-t.resetAfter)
+	for i, k := range t.keys(username, ip) {
+		ok, err := t.repo.RecordAttempt(ctx, k.scope, k.key, repository.AttemptLimits{
+			Free:        k.free,
+			BaseDelay:   t.baseDelay,
+			MaxLockout:  t.maxLockout,
+			ResetBefore: resetBefore,
+		})
+		if err != nil {
+			return err
+		}
+		if !ok {
+			if i > 0 {
+				t.releaseKey(ctx, t.keys(username, ip)[0])
+			}
+			return t.locked(ctx, username, ip)
+		}
+	}
+	return nil
+}
+
+// locked returns a *LoginLockedError for the longer of the two lockouts.
+func (t *loginThrottle) locked(ctx context.Context, username, ip string) error {
+	var retryAfter time.Duration
+	for _, k := range t.keys(username, ip) {
+		throttle, err := t.repo.GetThrottle(ctx, k.scope, k.key)
+		if err != nil {
+			return err
+		}
+		if throttle == nil || throttle.LockedUntil == nil {
+			continue
+		}
+		if wait := time.Until(*throttle.LockedUntil); wait > retryAfter {
+			retryAfter = wait
+		}
+	}
+	return &LoginLockedError{RetryAfter: max(retryAfter, time.Second)}
+}
+
+// release gives back an attempt that neither failed nor succeeded, such as
+// a login to a disabled account.
+func (t *loginThrottle) release(ctx context.Context, username, ip string) {
+	for _, k := range t.keys(username, ip) {
+		t.releaseKey(ctx, k)
+	}
+}
+
+// succeed clears the username counter and gives back the IP attempt. The
+// rest of the IP counter is left to expire so one valid account cannot be
+// used to keep resetting it.
+func (t *loginThrottle) succeed(ctx context.Context, username, ip string) {
+	if err := t.repo.Reset(ctx, models.ThrottleScopeUsername, username); err != nil {
+		t.log.Error().Err(err).Str("username", username).Msg("Failed to reset login throttle")
+	}
+	t.releaseKey(ctx, t.keys(username, ip)[1])
+}
+
+func (t *loginThrottle) releaseKey(ctx context.Context, k throttleKey) {
+	if err := t.repo.Release(ctx, k.scope, k.key); err != nil {
+		t.log.Error().Err(err).Str("scope", string(k.scope)).Msg("Failed to release login attempt")
+	}
+}
+
+func (t *loginThrottle) audit(ctx context.Context, username, ip string, success bool, reason string) {
+	err := t.repo.Audit(ctx, models.LoginAudit{
+		Username: username,
+		IP:       ip,
+		Success:  success,
+		Reason:   reason,
+	})
+	if err != nil {
+		t.log.Error().Err(err).Str("username", username).Msg("Failed to write login audit")
+	}
+}
+
+type throttleKey struct {
+	scope models.ThrottleScope
+	key   string
+	free  int
+}
+
+func (t *loginThrottle) keys(username, ip string) []throttleKey {
+	return []throttleKey{
+		{scope: models.ThrottleScopeUsername, key: username, free: t.userFreeAttempts},
+		{scope: models.ThrottleScopeIP, key: ip, free: t.ipFreeAttempts},
+	}
+}
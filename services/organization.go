@@ -0,0 +1,312 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/lib/pq"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/repository"
+	"github.com/rs/zerolog"
+)
+
+var (
+	ErrOrganizationNotFound = errors.New("organization not found")
+	ErrOrganizationExists   = errors.New("organization already exists")
+	ErrLastOwner            = errors.New("an organization needs at least one owner")
+)
+
+// Organization names appear in URLs, so they are lowercase slugs.
+var orgNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{1,38}[a-z0-9]$`)
+
+const maxOrgDisplayName = 100
+
+// access is what a user may do with schemes, given the organizations they
+// belong to.
+type access struct {
+	username string
+	roles    map[string]models.OrgRole
+}
+
+func loadAccess(ctx context.Context, orgRepo repository.OrganizationRepository, username string) (access, error) {
+	roles, err := orgRepo.GetRoles(ctx, username)
+	if err != nil {
+		return access{}, err
+	}
+	return access{username: username, roles: roles}, nil
+}
+
+// canView reports whether the user may see a scheme: a public one, a
+// personal one of theirs or one owned by an organization they belong to.
+func (a access) canView(public bool, author string, org *string) bool {
+	if public {
+		return true
+	}
+	if org == nil {
+		return author == a.username
+	}
+	_, ok := a.roles[*org]
+	return ok
+}
+
+// canEdit reports whether the user may change a scheme: a personal one of
+// theirs or one owned by an organization where they are an editor or owner.
+func (a access) canEdit(author string, org *string) bool {
+	if org == nil {
+		return author == a.username
+	}
+	return a.roles[*org].CanEdit()
+}
+
+type OrganizationService interface {
+	CreateOrganization(ctx context.Context, username string, req models.OrganizationRequest) (*models.Organization, error)
+	ListOrganizations(ctx context.Context, username string) ([]models.Organization, error)
+	GetOrganization(ctx context.Context, username, name string) (*models.Organization, error)
+	DeleteOrganization(ctx context.Context, username, name string) error
+	SetMember(ctx context.Context, username, name, member string, role models.OrgRole) error
+	RemoveMember(ctx context.Context, username, name, member string) error
+	GetOrganizationColorSchemes(ctx context.Context, username, name string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error)
+}
+
+type organizationService struct {
+	orgRepo         repository.OrganizationRepository
+	userRepo        repository.UserRepository
+	colorSchemeRepo repository.ColorSchemeRepository
+	log             zerolog.Logger
+}
+
+func NewOrganizationService(orgRepo repository.OrganizationRepository, userRepo repository.UserRepository, colorSchemeRepo repository.ColorSchemeRepository, log zerolog.Logger) OrganizationService {
+	return &organizationService{
+		orgRepo:         orgRepo,
+		userRepo:        userRepo,
+		colorSchemeRepo: colorSchemeRepo,
+		log:             log,
+	}
+}
+
+// CreateOrganization creates an organization with username as its owner.
+func (s *organizationService) CreateOrganization(ctx context.Context, username string, req models.OrganizationRequest) (*models.Organization, error) {
+	var fields []models.FieldError
+
+	name := strings.ToLower(strings.TrimSpace(req.Name))
+	if !orgNamePattern.MatchString(name) {
+		fields = append(fields, models.FieldError{Field: "name", Code: "invalid", Message: "name must be 3-40 lowercase letters, digits or hyphens, not starting or ending with a hyphen"})
+	}
+
+	displayName := strings.TrimSpace(req.DisplayName)
+	if displayName == "" {
+		displayName = name
+	}
+	if len(displayName) > maxOrgDisplayName {
+		fields = append(fields, models.FieldError{Field: "display_name", Code: "too_long", Message: fmt.Sprintf("display name must be at most %d characters", maxOrgDisplayName)})
+	}
+
+	if len(fields) > 0 {
+		return nil, &ValidationError{Fields: fields}
+	}
+
+	org := models.Organization{Name: name, DisplayName: displayName}
+	if err := s.orgRepo.Create(ctx, org, username); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return nil, ErrOrganizationExists
+		}
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to create organization")
+		return nil, err
+	}
+
+	return s.GetOrganization(ctx, username, name)
+}
+
+func (s *organizationService) ListOrganizations(ctx context.Context, username string) ([]models.Organization, error) {
+	orgs, err := s.orgRepo.ListByMember(ctx, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to list organizations")
+		return nil, err
+	}
+
+	if len(orgs) == 0 {
+		orgs = []models.Organization{}
+	}
+
+	return orgs, nil
+}
+
+// GetOrganization returns an organization username belongs to with its
+// members.
+func (s *organizationService) GetOrganization(ctx context.Context, username, name string) (*models.Organization, error) {
+	org, role, err := s.membership(ctx, username, name)
+	if err != nil {
+		return nil, err
+	}
+	org.Role = role
+
+	if org.Members, err = s.orgRepo.GetMembers(ctx, name); err != nil {
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to get organization members")
+		return nil, err
+	}
+
+	return org, nil
+}
+
+// DeleteOrganization deletes an organization username owns. Its schemes go
+// back to their authors as personal schemes.
+func (s *organizationService) DeleteOrganization(ctx context.Context, username, name string) error {
+	if err := s.requireOwner(ctx, username, name); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.Delete(ctx, name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrOrganizationNotFound
+		}
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to delete organization")
+		return err
+	}
+
+	s.log.Info().Str("actor", username).Str("organization", name).Msg("Organization deleted")
+	return nil
+}
+
+// SetMember adds member to an organization username owns or changes their
+// role. The last owner can't be demoted.
+func (s *organizationService) SetMember(ctx context.Context, username, name, member string, role models.OrgRole) error {
+	if !role.Valid() {
+		return &ValidationError{Fields: []models.FieldError{{Field: "role", Code: "invalid", Message: "role must be owner, editor or viewer"}}}
+	}
+
+	if err := s.requireOwner(ctx, username, name); err != nil {
+		return err
+	}
+
+	if _, err := s.userRepo.GetByUsername(ctx, member); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.log.Error().Err(err).Str("username", member).Msg("Failed to get user")
+		return err
+	}
+
+	if role != models.OrgRoleOwner {
+		if err := s.keepOwner(ctx, name, member); err != nil {
+			return err
+		}
+	}
+
+	if err := s.orgRepo.SetMember(ctx, name, member, role); err != nil {
+		s.log.Error().Err(err).Str("organization", name).Str("username", member).Msg("Failed to set organization member")
+		return err
+	}
+
+	s.log.Info().Str("actor", username).Str("organization", name).Str("username", member).Str("role", string(role)).Msg("Organization member set")
+	return nil
+}
+
+// RemoveMember removes member from an organization. Owners may remove
+// anyone and members may leave, but the last owner can't.
+func (s *organizationService) RemoveMember(ctx context.Context, username, name, member string) error {
+	if username != member {
+		if err := s.requireOwner(ctx, username, name); err != nil {
+			return err
+		}
+	} else if _, _, err := s.membership(ctx, username, name); err != nil {
+		return err
+	}
+
+	if err := s.keepOwner(ctx, name, member); err != nil {
+		return err
+	}
+
+	if err := s.orgRepo.RemoveMember(ctx, name, member); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrUserNotFound
+		}
+		s.log.Error().Err(err).Str("organization", name).Str("username", member).Msg("Failed to remove organization member")
+		return err
+	}
+
+	s.log.Info().Str("actor", username).Str("organization", name).Str("username", member).Msg("Organization member removed")
+	return nil
+}
+
+// GetOrganizationColorSchemes lists the schemes of an organization username
+// belongs to.
+func (s *organizationService) GetOrganizationColorSchemes(ctx context.Context, username, name string, filter models.ColorSchemeFilter) ([]models.ColorScheme, error) {
+	if _, _, err := s.membership(ctx, username, name); err != nil {
+		return nil, err
+	}
+
+	colorSchemes, err := s.colorSchemeRepo.GetByOrganization(ctx, name, filter)
+	if err != nil {
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to get organization color schemes")
+		return nil, err
+	}
+
+	if len(colorSchemes) == 0 {
+		colorSchemes = []models.ColorScheme{}
+	}
+
+	return colorSchemes, nil
+}
+
+// membership loads an organization and username's role in it. Non-members
+// are told it doesn't exist.
+func (s *organizationService) membership(ctx context.Context, username, name string) (*models.Organization, models.OrgRole, error) {
+	org, err := s.orgRepo.GetByName(ctx, name)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, "", ErrOrganizationNotFound
+		}
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to get organization")
+		return nil, "", err
+	}
+
+	roles, err := s.orgRepo.GetRoles(ctx, username)
+	if err != nil {
+		s.log.Error().Err(err).Str("username", username).Msg("Failed to get organization roles")
+		return nil, "", err
+	}
+
+	role, ok := roles[name]
+	if !ok {
+		return nil, "", ErrOrganizationNotFound
+	}
+	return org, role, nil
+}
+
+func (s *organizationService) requireOwner(ctx context.Context, username, name string) error {
+	_, role, err := s.membership(ctx, username, name)
+	if err != nil {
+		return err
+	}
+	if role != models.OrgRoleOwner {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// keepOwner fails when member is the organization's only owner, so that
+// demoting or removing them would leave it without one.
+func (s *organizationService) keepOwner(ctx context.Context, name, member string) error {
+	members, err := s.orgRepo.GetMembers(ctx, name)
+	if err != nil {
+		s.log.Error().Err(err).Str("organization", name).Msg("Failed to get organization members")
+		return err
+	}
+
+	owners, isOwner := 0, false
+	for _, m := range members {
+		if m.Role == models.OrgRoleOwner {
+			owners++
+			isOwner = isOwner || m.Username == member
+		}
+	}
+	if isOwner && owners == 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
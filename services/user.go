@@ -2,67 +2,213 @@ package services
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"fmt"
 	"strings"
+	"time"
 
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/config"
+	"github.com/nqvinh00/colorscheme/pkg/mailer"
+	"github.com/nqvinh00/colorscheme/pkg/policy"
 	"github.com/nqvinh00/colorscheme/pkg/utils"
 	"github.com/nqvinh00/colorscheme/repository"
 
+	"github.com/lib/pq"
 	"github.com/rs/zerolog"
 	"golang.org/x/crypto/bcrypt"
 )
 
+const (
+	emailVerificationTTL = 24 * time.Hour
+	passwordResetTTL     = time.Hour
+
+	// Postgres unique_violation
+	uniqueViolation = "23505"
+)
+
+var (
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrAccountDisabled    = errors.New("account disabled")
+	ErrUserNotFound       = errors.New("user not found")
+)
+
+var (
+	usernameTakenError = models.FieldError{Field: "username", Code: "taken", Message: "Username is already taken"}
+	emailTakenError    = models.FieldError{Field: "email", Code: "taken", Message: "Email is already in use"}
+)
+
+// ValidationError lists every field that failed validation so clients can
+// show all problems at once.
+type ValidationError struct {
+	Fields []models.FieldError
+}
+
+func (e *ValidationError) Error() string {
+	return "validation failed"
+}
+
 type UserService interface {
-	Login(ctx context.Context, username, password string) (string, error)
-	CreateAccount(ctx context.Context, username, password string) (string, error)
+	Login(ctx context.Context, username, password, ip string) (*models.LoginResult, error)
+	CreateAccount(ctx context.Context, username, password, email string) (string, error)
+	UpdateEmail(ctx context.Context, username, email string) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, password string) error
+	EnrollTOTP(ctx context.Context, username string) (*models.TOTPEnrollment, error)
+	ConfirmTOTP(ctx context.Context, username, code string) ([]string, error)
+	DisableTOTP(ctx context.Context, username, code, ip string) error
+	RegenerateRecoveryCodes(ctx context.Context, username, code, ip string) ([]string, error)
+	VerifyMFA(ctx context.Context, mfaToken, code, ip string) (string, error)
+	AccountStatus(ctx context.Context, username string) (models.Role, bool, error)
 }
 
 type userService struct {
 	userRepo  repository.UserRepository
+	throttle  *loginThrottle
+	mailer    mailer.Mailer
+	policy    *policy.Policy
 	log       zerolog.Logger
 	secretKey string
+	baseURL   string
+	dummyHash []byte
 }
 
-func NewUserService(userRepo repository.UserRepository, log zerolog.Logger, secretKey string) UserService {
+func NewUserService(
+	userRepo repository.UserRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	mailer mailer.Mailer,
+	policy *policy.Policy,
+	log zerolog.Logger,
+	secretKey, baseURL string,
+	loginCfg config.LoginConfig,
+) UserService {
+	// Compared against when the username does not exist so unknown users
+	// take as long to reject as wrong passwords
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("colorscheme-dummy-password"), bcrypt.DefaultCost)
+
 	return &userService{
 		userRepo:  userRepo,
+		throttle:  newLoginThrottle(loginAttemptRepo, log, loginCfg),
+		mailer:    mailer,
+		policy:    policy,
 		log:       log,
 		secretKey: secretKey,
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		dummyHash: dummyHash,
 	}
 }
 
-func (s *userService) Login(ctx context.Context, username, password string) (string, error) {
+func (s *userService) Login(ctx context.Context, username, password, ip string) (*models.LoginResult, error) {
+	if err := s.throttle.attempt(ctx, username, ip); err != nil {
+		var locked *LoginLockedError
+		if errors.As(err, &locked) {
+			s.log.Warn().Str("username", username).Str("ip", ip).Msg("Login rejected, locked out")
+			s.throttle.audit(ctx, username, ip, false, "locked")
+		} else {
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to check login throttle")
+		}
+		return nil, err
+	}
+
 	var hashed string
+	known := true
 	if err := s.userRepo.Login(ctx, username, &hashed); err != nil {
-		s.log.Error().Str("username", username).Err(err).Msg("Failed to login")
-		return "", err
+		if !errors.Is(err, sql.ErrNoRows) {
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to login")
+			s.throttle.release(ctx, username, ip)
+			return nil, err
+		}
+		hashed = string(s.dummyHash)
+		known = false
+	}
+
+	matched := bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) == nil
+	if !known || !matched {
+		s.log.Warn().Str("username", username).Str("ip", ip).Msg("Login failed")
+		s.throttle.audit(ctx, username, ip, false, "invalid_credentials")
+		return nil, ErrInvalidCredentials
+	}
+
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to get user")
+		s.throttle.release(ctx, username, ip)
+		return nil, err
+	}
+
+	if user.Disabled {
+		s.log.Warn().Str("username", username).Str("ip", ip).Msg("Login rejected, account disabled")
+		s.throttle.release(ctx, username, ip)
+		s.throttle.audit(ctx, username, ip, false, "disabled")
+		return nil, ErrAccountDisabled
 	}
 
-	if bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password)) != nil {
-		s.log.Error().Str("username", username).Msg("Invalid password")
-		return "", errors.New("invalid password")
+	// The password step only counts as a full login once the second factor
+	// is verified by VerifyMFA
+	if user.TOTPEnabled {
+		mfaToken, err := utils.GenerateMFAToken(username, s.secretKey)
+		if err != nil {
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to generate MFA token")
+			s.throttle.release(ctx, username, ip)
+			return nil, err
+		}
+		s.throttle.release(ctx, username, ip)
+		s.throttle.audit(ctx, username, ip, false, "mfa_pending")
+		return &models.LoginResult{MFARequired: true, MFAToken: mfaToken}, nil
 	}
 
-	token, err := utils.GenerateToken(username, s.secretKey)
+	token, err := utils.GenerateToken(username, string(user.Role), s.secretKey)
 	if err != nil {
 		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate token")
-		return "", err
+		s.throttle.release(ctx, username, ip)
+		return nil, err
 	}
 
-	return token, nil
+	s.throttle.succeed(ctx, username, ip)
+	s.throttle.audit(ctx, username, ip, true, "ok")
+
+	return &models.LoginResult{Token: token}, nil
 }
 
-func (s *userService) CreateAccount(ctx context.Context, username, password string) (string, error) {
+func (s *userService) CreateAccount(ctx context.Context, username, password, email string) (string, error) {
+	fields := append(s.policy.ValidateUsername(username), s.policy.ValidatePassword(password, username)...)
+	if len(fields) == 0 {
+		taken, err := s.userRepo.UsernameTaken(ctx, username)
+		if err != nil {
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to check username")
+			return "", err
+		}
+		if taken {
+			fields = append(fields, usernameTakenError)
+		}
+	}
+	if len(fields) > 0 {
+		return "", &ValidationError{Fields: fields}
+	}
+
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
 		s.log.Error().Str("username", username).Err(err).Msg("Failed to hash password")
 		return "", err
 	}
 
+	var emailPtr *string
+	if email != "" {
+		emailPtr = &email
+	}
+
 	s.log.Info().Str("username", username).Msg("Creating account")
-	if err := s.userRepo.CreateAccount(ctx, username, string(hashed)); err != nil {
-		if strings.Contains(err.Error(), "UNIQUE constraint failed") {
-			return "", errors.New("user already exists")
+	if err := s.userRepo.CreateAccount(ctx, username, string(hashed), emailPtr); err != nil {
+		// Lost a race with a concurrent registration
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			if strings.Contains(pqErr.Constraint, "email") {
+				return "", &ValidationError{Fields: []models.FieldError{emailTakenError}}
+			}
+			return "", &ValidationError{Fields: []models.FieldError{usernameTakenError}}
 		}
 
 		s.log.Error().Str("username", username).Err(err).Msg("Failed to create user")
@@ -70,7 +216,14 @@ func (s *userService) CreateAccount(ctx context.Context, username, password stri
 
 	}
 
-	token, err := utils.GenerateToken(username, s.secretKey)
+	if email != "" {
+		// The account exists at this point, a failed email can be resent later
+		if err := s.sendVerification(ctx, username, email); err != nil {
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to send verification email")
+		}
+	}
+
+	token, err := utils.GenerateToken(username, string(models.RoleUser), s.secretKey)
 	if err != nil {
 		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate token")
 		return "", err
@@ -78,3 +231,182 @@ func (s *userService) CreateAccount(ctx context.Context, username, password stri
 
 	return token, nil
 }
+
+func (s *userService) UpdateEmail(ctx context.Context, username, email string) error {
+	if err := s.userRepo.UpdateEmail(ctx, username, email); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+			return &ValidationError{Fields: []models.FieldError{emailTakenError}}
+		}
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to update email")
+		return err
+	}
+
+	if err := s.sendVerification(ctx, username, email); err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to send verification email")
+		return err
+	}
+
+	return nil
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	userToken, err := s.userRepo.ConsumeToken(ctx, utils.HashToken(token), models.TokenPurposeEmailVerification)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		s.log.Error().Err(err).Msg("Failed to consume verification token")
+		return err
+	}
+
+	// The email may have changed since the token was issued
+	if err := s.userRepo.MarkEmailVerified(ctx, userToken.Username, userToken.Email); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		s.log.Error().Str("username", userToken.Username).Err(err).Msg("Failed to verify email")
+		return err
+	}
+
+	return nil
+}
+
+// RequestPasswordReset sends a reset link if email belongs to a verified
+// account. It reports success either way so callers cannot probe for
+// registered addresses.
+func (s *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.userRepo.GetByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		s.log.Error().Err(err).Msg("Failed to look up user by email")
+		return err
+	}
+
+	if !user.EmailVerified {
+		return nil
+	}
+
+	token, err := s.issueToken(ctx, user.Username, email, models.TokenPurposePasswordReset, passwordResetTTL)
+	if err != nil {
+		s.log.Error().Str("username", user.Username).Err(err).Msg("Failed to issue password reset token")
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Someone requested a password reset for %s.\n\nOpen the link below within %s to choose a new password:\n%s/reset-password?token=%s\n\nIf this wasn't you, ignore this email.\n",
+			user.Username, passwordResetTTL, s.baseURL, token,
+		),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.log.Error().Str("username", user.Username).Err(err).Msg("Failed to send password reset email")
+		return err
+	}
+
+	return nil
+}
+
+func (s *userService) ResetPassword(ctx context.Context, token, password string) error {
+	// The password is checked against the token's user before redeeming, so
+	// a rejected password does not burn the token
+	pending, err := s.userRepo.GetToken(ctx, utils.HashToken(token), models.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		s.log.Error().Err(err).Msg("Failed to get password reset token")
+		return err
+	}
+	if fields := s.policy.ValidatePassword(password, pending.Username); len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+
+	userToken, err := s.userRepo.ConsumeToken(ctx, utils.HashToken(token), models.TokenPurposePasswordReset)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		s.log.Error().Err(err).Msg("Failed to consume password reset token")
+		return err
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		s.log.Error().Str("username", userToken.Username).Err(err).Msg("Failed to hash password")
+		return err
+	}
+
+	if err := s.userRepo.UpdatePassword(ctx, userToken.Username, string(hashed)); err != nil {
+		s.log.Error().Str("username", userToken.Username).Err(err).Msg("Failed to update password")
+		return err
+	}
+
+	// Invalidate any other outstanding reset links
+	if err := s.userRepo.DeleteTokens(ctx, userToken.Username, models.TokenPurposePasswordReset); err != nil {
+		s.log.Error().Str("username", userToken.Username).Err(err).Msg("Failed to delete password reset tokens")
+	}
+
+	return nil
+}
+
+func (s *userService) sendVerification(ctx context.Context, username, email string) error {
+	token, err := s.issueToken(ctx, username, email, models.TokenPurposeEmailVerification, emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm this email address by opening the link below within %s:\n%s/verify-email?token=%s\n",
+			username, emailVerificationTTL, s.baseURL, token,
+		),
+	})
+}
+
+// issueToken replaces any outstanding token of the same purpose with a fresh
+// one and returns the plaintext value to be emailed.
+func (s *userService) issueToken(ctx context.Context, username, email string, purpose models.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := s.userRepo.DeleteTokens(ctx, username, purpose); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	err = s.userRepo.CreateToken(ctx, models.UserToken{
+		TokenHash: utils.HashToken(token),
+		Username:  username,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Now().Add(ttl),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// AccountStatus returns username's current role and whether tokens issued
+// to them must be rejected. Unknown users count as disabled.
+func (s *userService) AccountStatus(ctx context.Context, username string) (models.Role, bool, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", true, nil
+		}
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to get user")
+		return "", false, err
+	}
+
+	return user.Role, user.Disabled, nil
+}
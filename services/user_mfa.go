@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/nqvinh00/colorscheme/models"
+	"github.com/nqvinh00/colorscheme/pkg/totp"
+	"github.com/nqvinh00/colorscheme/pkg/utils"
+)
+
+const (
+	totpIssuer        = "ColorScheme"
+	totpSkew          = 1
+	recoveryCodeCount = 10
+)
+
+var (
+	ErrInvalidCode        = errors.New("invalid two-factor code")
+	ErrTOTPAlreadyEnabled = errors.New("two-factor authentication already enabled")
+	ErrTOTPNotEnabled     = errors.New("two-factor authentication not enabled")
+)
+
+// EnrollTOTP generates a new pending secret. Two-factor authentication only
+// takes effect once ConfirmTOTP succeeds.
+func (s *userService) EnrollTOTP(ctx context.Context, username string) (*models.TOTPEnrollment, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate TOTP secret")
+		return nil, err
+	}
+
+	if err := s.userRepo.SetTOTPSecret(ctx, username, secret); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTOTPAlreadyEnabled
+		}
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to store TOTP secret")
+		return nil, err
+	}
+
+	return &models.TOTPEnrollment{
+		Secret: secret,
+		URI:    totp.URI(totpIssuer, username, secret),
+	}, nil
+}
+
+// ConfirmTOTP enables two-factor authentication and returns the recovery
+// codes. They are only ever shown here, the database keeps their hashes.
+func (s *userService) ConfirmTOTP(ctx context.Context, username, code string) ([]string, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to get user")
+		return nil, err
+	}
+
+	if user.TOTPEnabled {
+		return nil, ErrTOTPAlreadyEnabled
+	}
+	if user.TOTPSecret == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	counter, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate recovery codes")
+		return nil, err
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, username, counter, hashes); err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to enable TOTP")
+		return nil, err
+	}
+
+	s.log.Info().Str("username", username).Msg("Two-factor authentication enabled")
+	return codes, nil
+}
+
+// DisableTOTP turns two-factor authentication off. Wrong codes count
+// towards the login lockout, as in VerifyMFA.
+func (s *userService) DisableTOTP(ctx context.Context, username, code, ip string) error {
+	user, err := s.throttledSecondFactor(ctx, username, code, ip)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.Username); err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to disable TOTP")
+		s.throttle.release(ctx, username, ip)
+		return err
+	}
+
+	s.throttle.succeed(ctx, username, ip)
+	s.throttle.audit(ctx, username, ip, true, "ok_mfa_disable")
+	s.log.Info().Str("username", username).Msg("Two-factor authentication disabled")
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes. Wrong codes count
+// towards the login lockout, as in VerifyMFA.
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, username, code, ip string) ([]string, error) {
+	if _, err := s.throttledSecondFactor(ctx, username, code, ip); err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate recovery codes")
+		s.throttle.release(ctx, username, ip)
+		return nil, err
+	}
+
+	if err := s.userRepo.ReplaceRecoveryCodes(ctx, username, hashes); err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to store recovery codes")
+		s.throttle.release(ctx, username, ip)
+		return nil, err
+	}
+
+	s.throttle.succeed(ctx, username, ip)
+	s.throttle.audit(ctx, username, ip, true, "ok_recovery_codes")
+	return codes, nil
+}
+
+// VerifyMFA exchanges the token returned by Login plus a TOTP or recovery
+// code for a session token. Wrong codes count towards the login lockout.
+func (s *userService) VerifyMFA(ctx context.Context, mfaToken, code, ip string) (string, error) {
+	token, err := utils.ValidateToken(mfaToken, s.secretKey)
+	if err != nil || !token.Valid {
+		return "", ErrInvalidToken
+	}
+
+	claims := token.Claims.(jwt.MapClaims)
+	username, _ := claims["username"].(string)
+	if pending, _ := claims["mfa_pending"].(bool); !pending || username == "" {
+		return "", ErrInvalidToken
+	}
+
+	user, err := s.throttledSecondFactor(ctx, username, code, ip)
+	if err != nil {
+		return "", err
+	}
+
+	if user.Disabled {
+		s.throttle.release(ctx, username, ip)
+		s.throttle.audit(ctx, username, ip, false, "disabled")
+		return "", ErrAccountDisabled
+	}
+
+	sessionToken, err := utils.GenerateToken(username, string(user.Role), s.secretKey)
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to generate token")
+		s.throttle.release(ctx, username, ip)
+		return "", err
+	}
+
+	s.throttle.succeed(ctx, username, ip)
+	s.throttle.audit(ctx, username, ip, true, "ok_mfa")
+
+	return sessionToken, nil
+}
+
+// throttledSecondFactor is requireSecondFactor behind the login throttle:
+// it refuses locked out usernames and IPs and counts wrong codes. Callers
+// finish the attempt with succeed or release once the code is accepted.
+func (s *userService) throttledSecondFactor(ctx context.Context, username, code, ip string) (*models.User, error) {
+	if err := s.throttle.attempt(ctx, username, ip); err != nil {
+		return nil, err
+	}
+
+	user, err := s.requireSecondFactor(ctx, username, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidCode) {
+			s.log.Warn().Str("username", username).Str("ip", ip).Msg("Two-factor verification failed")
+			s.throttle.audit(ctx, username, ip, false, "invalid_mfa_code")
+		} else {
+			s.throttle.release(ctx, username, ip)
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// requireSecondFactor accepts either a current TOTP code or an unused
+// recovery code for a user with two-factor authentication enabled.
+func (s *userService) requireSecondFactor(ctx context.Context, username, code string) (*models.User, error) {
+	user, err := s.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to get user")
+		return nil, err
+	}
+
+	if !user.TOTPEnabled || user.TOTPSecret == nil {
+		return nil, ErrTOTPNotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+	if len(code) == totp.Digits {
+		counter, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), totpSkew)
+		if !ok {
+			return nil, ErrInvalidCode
+		}
+		if err := s.userRepo.UseTOTPCounter(ctx, username, counter); err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrInvalidCode
+			}
+			s.log.Error().Str("username", username).Err(err).Msg("Failed to record TOTP counter")
+			return nil, err
+		}
+		return user, nil
+	}
+
+	if err := s.userRepo.ConsumeRecoveryCode(ctx, username, utils.HashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCode
+		}
+		s.log.Error().Str("username", username).Err(err).Msg("Failed to consume recovery code")
+		return nil, err
+	}
+
+	s.log.Info().Str("username", username).Msg("Recovery code used")
+	return user, nil
+}
+
+// generateRecoveryCodes returns display codes formatted as XXXX-XXXX-XXXX-XXXX
+// together with the hashes to store.
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		raw := base32.StdEncoding.EncodeToString(b)
+		codes[i] = raw[0:4] + "-" + raw[4:8] + "-" + raw[8:12] + "-" + raw[12:16]
+		hashes[i] = utils.HashToken(raw)
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}